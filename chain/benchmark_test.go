@@ -0,0 +1,179 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Create block tree of size blocks, most blocks extend one of the latest blocks so forks stay short.
+func newBenchmarkChain(size int) (*Chain, []*Block) {
+
+	chain := newTestChain(1)
+	random := rand.New(rand.NewSource(1))
+
+	blocks := make([]*Block, 0, size)
+	for i := 0; i < size; i++ {
+
+		parentHash := chain.genesisBlock.block.Hash
+		if len(blocks) > 0 {
+			back := random.Intn(minInt(3, len(blocks)))
+			parentHash = blocks[len(blocks)-1-back].Hash
+		}
+
+		block := &Block{Shard: 1, ParentHash: parentHash, Validator: fmt.Sprintf("block %d", i)}
+		block.SetHash()
+		chain.Insert(block)
+		blocks = append(blocks, block)
+	}
+
+	return chain, blocks
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func BenchmarkInsert(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		newBenchmarkChain(1000)
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+
+	chain, blocks := newBenchmarkChain(1000)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if chain.Search(blocks[n%len(blocks)].Hash) == nil {
+			b.Fatal("block not found")
+		}
+	}
+}
+
+func BenchmarkBlockInLongestChain(b *testing.B) {
+
+	chain, blocks := newBenchmarkChain(1000)
+	chainBlocks := make([]*ChainBlock, len(blocks))
+	for i, block := range blocks {
+		chainBlocks[i] = chain.Search(block.Hash)
+	}
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		chain.BlockInLongestChain(chainBlocks[n%len(chainBlocks)])
+	}
+}
+
+func BenchmarkUpdateVisualisation(b *testing.B) {
+
+	chain, _ := newBenchmarkChain(1000)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		chain.UpdateVisualisation(true)
+	}
+}
+
+func BenchmarkGetLongestChainTXOutList(b *testing.B) {
+
+	chain, _ := newBenchmarkChain(1000)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		chain.GetLongestChainTXOutList()
+	}
+}
+
+// Create chains of shardCount shards with a previous finalisation of count inconsistent transactions,
+// the blocks of every shard process half of the inconsistent transactions it receives.
+func newBenchmarkFinalisation(shardCount int, count int) ([]Chain, *Finalisation) {
+
+	chains := newTestChains(shardCount)
+	random := rand.New(rand.NewSource(1))
+
+	previous := emptyFinalisation()
+	txIn := make([][]*Transaction, shardCount+1)
+	for i := 0; i < count; i++ {
+		source := 1 + random.Intn(shardCount)
+		target := 1 + (source+random.Intn(shardCount-1))%shardCount
+		tx := newTestTransaction(source, target, fmt.Sprintf("inconsistent %d", i))
+		previous.InconsistentTX = append(previous.InconsistentTX, tx)
+		if random.Intn(2) == 0 {
+			txIn[target] = append(txIn[target], tx)
+		}
+	}
+
+	const blocks = 10
+	for shard := 1; shard <= shardCount; shard++ {
+		parent := chains[shard].genesisBlock
+		for height := 0; height < blocks; height++ {
+
+			txOut := make([]*Transaction, count/(blocks*shardCount))
+			for i := range txOut {
+				txOut[i] = newTestTransaction(shard, 1+(shard%shardCount), fmt.Sprintf("out %d-%d-%d", shard, height, i))
+			}
+
+			block := Block{
+				Shard:      shard,
+				ParentHash: parent.block.Hash,
+				TXIn:       txIn[shard][height*len(txIn[shard])/blocks : (height+1)*len(txIn[shard])/blocks],
+				TXOut:      txOut,
+				Validator:  fmt.Sprintf("block %d-%d", shard, height),
+			}
+			block.SetHash()
+			chains[shard].Insert(&block)
+			parent = chains[shard].Search(block.Hash)
+		}
+	}
+
+	return chains, previous
+}
+
+func BenchmarkCalculateFinalisation(b *testing.B) {
+	for _, count := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("inconsistentTX=%d", count), func(b *testing.B) {
+
+			chains, previous := newBenchmarkFinalisation(4, count)
+			for i, block := range CalculateFinalisation(chains, previous).Blocks {
+				if !bytes.Equal(block.Hash, chains[i+1].GetLongestChains(1, false)[0].Block().Hash) {
+					b.Fatalf("shard %d not finalised up to its longest chain", i+1)
+				}
+			}
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				CalculateFinalisation(chains, previous)
+			}
+		})
+	}
+}
+
+func BenchmarkUpdateConsistency(b *testing.B) {
+	for _, count := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("txOut=%d", count), func(b *testing.B) {
+
+			chains, previous := newBenchmarkFinalisation(4, count)
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				chains[1].UpdateConsistency(previous.InconsistentTX)
+			}
+		})
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+
+	chain, _ := newBenchmarkChain(1000)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		chain.Copy()
+	}
+}
@@ -0,0 +1,171 @@
+// Package chain implements the block trees of shards and the Guaranteed-TX finalisation algorithm.
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+)
+
+type Block struct {
+	Shard      int            `json:"shard"`
+	Hash       []byte         `json:"hash"`
+	ParentHash []byte         `json:"parentHash"`
+	Slot       int            `json:"slot"`
+	TXIn       []*Transaction `json:"txIn"`
+	TXOut      []*Transaction `json:"txOut"`
+	Validator  string         `json:"validator"`
+	Signature  []byte         `json:"signature,omitempty"`
+}
+
+// Part of a block covered by its hash and signature, transactions by hash.
+// Every slot has one proposer, slots increase along a chain.
+type Header struct {
+	Shard      int
+	ParentHash []byte
+	Slot       int
+	TXIn       []string
+	TXOut      []string
+	Validator  string
+}
+
+type ChainBlock struct {
+	height     int
+	block      *Block
+	parent     *ChainBlock
+	children   []*ChainBlock
+	valid      bool
+	finalised  bool
+	coordinate Coordinate
+	canonical  uint64
+}
+
+// Status of a block in the visualisation
+type Status int
+
+const (
+	StatusGenesis Status = iota
+	StatusFinalised
+	StatusFinalisedOther
+	StatusInvalid
+	StatusCanonical
+	StatusStale
+	StatusPruned
+)
+
+// Coordinate of a block in the visualisation, X in pixels since start.
+type Coordinate struct {
+	X      float32 `json:"x"`
+	Y      float32 `json:"y"`
+	Status Status  `json:"status"`
+}
+
+func (chainBlock *ChainBlock) Height() int {
+	return chainBlock.height
+}
+
+func (chainBlock *ChainBlock) Block() *Block {
+	return chainBlock.block
+}
+
+func (chainBlock *ChainBlock) Parent() *ChainBlock {
+	return chainBlock.parent
+}
+
+func (chainBlock *ChainBlock) Children() []*ChainBlock {
+	return chainBlock.children
+}
+
+func (chainBlock *ChainBlock) Valid() bool {
+	return chainBlock.valid
+}
+
+func (chainBlock *ChainBlock) Finalised() bool {
+	return chainBlock.finalised
+}
+
+func (chainBlock *ChainBlock) Coordinate() Coordinate {
+	return chainBlock.coordinate
+}
+
+// Header of the block
+func (block *Block) Header() Header {
+
+	header := Header{
+		Shard:      block.Shard,
+		ParentHash: block.ParentHash,
+		Slot:       block.Slot,
+		TXIn:       make([]string, len(block.TXIn)),
+		TXOut:      make([]string, len(block.TXOut)),
+		Validator:  block.Validator,
+	}
+	for i, tx := range block.TXIn {
+		header.TXIn[i] = tx.Hash
+	}
+	for i, tx := range block.TXOut {
+		header.TXOut[i] = tx.Hash
+	}
+	return header
+}
+
+// Hash of the header
+func (header Header) Hash() []byte {
+
+	var encoded bytes.Buffer
+
+	encoder := gob.NewEncoder(&encoded)
+	encoder.Encode(header)
+
+	hash := sha256.Sum256(encoded.Bytes())
+	return hash[:]
+}
+
+// Calculate Hash of Block from its header, a signature is invalidated.
+func (block *Block) SetHash() {
+	block.Hash = block.Header().Hash()
+	block.Signature = nil
+}
+
+// Update inconsistency of chain based on txOut of other chains.
+func (chainBlock *ChainBlock) updateConsistency(txOutSet *TxSet, recorder Recorder) {
+
+	// Validate consistent txIn, processed transactions are added back after visiting the children
+	processed := make([]*Transaction, 0, len(chainBlock.block.TXIn))
+	defer func() {
+		for _, txIn := range processed {
+			txOutSet.Add(txIn)
+		}
+	}()
+
+	for _, txIn := range chainBlock.block.TXIn {
+
+		if txOutSet.Remove(txIn) {
+			processed = append(processed, txIn)
+		} else if !chainBlock.finalised {
+			//  Only invalidate blocks which are not final
+			chainBlock.invalidateBlock(recorder)
+			return
+		}
+	}
+
+	// Re-validate block.
+	chainBlock.valid = true
+
+	// Validate children, every child sees the same set of transactions
+	for _, child := range chainBlock.children {
+		child.updateConsistency(txOutSet, recorder)
+	}
+}
+
+func (chainBlock *ChainBlock) invalidateBlock(recorder Recorder) {
+
+	// Record blocks which become invalid
+	if chainBlock.valid && recorder != nil {
+		recorder.BlockInvalidated(chainBlock.block)
+	}
+
+	chainBlock.valid = false
+	for _, child := range chainBlock.children {
+		child.invalidateBlock(recorder)
+	}
+}
@@ -0,0 +1,537 @@
+package chain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Horizontal scale of block coordinates
+const PixelsPerSecond = 5
+
+type Chain struct {
+	genesisBlock       *ChainBlock
+	lastFinalisedBlock *ChainBlock
+	clock              clock.Clock
+	recorder           Recorder
+	orphans            orphanPool
+
+	// Index of all blocks by hash
+	blocks map[string]*ChainBlock
+
+	// Canonical head and its ancestors (marked with the version) are cached until the tree changes
+	version     uint64
+	head        *ChainBlock
+	headVersion uint64
+
+	// Number of blocks removed by pruning
+	pruned int
+
+	// Proposer verification of inserted blocks, and the number of blocks it rejected
+	verifier Verifier
+	rejected int
+}
+
+// Recorder of changes in the block tree, see package trace.
+type Recorder interface {
+	BlockInvalidated(block *Block)
+	BlockFinalised(block *Block)
+}
+
+func (chain *Chain) Init(shard int, clock clock.Clock) {
+
+	chain.clock = clock
+
+	block := Block{
+		Shard:      shard,
+		Hash:       []byte(fmt.Sprintf("Shard %d", shard)),
+		ParentHash: nil,
+		TXIn:       nil,
+		TXOut:      nil,
+	}
+
+	coordinate := Coordinate{
+		X:      0,
+		Y:      0,
+		Status: StatusGenesis,
+	}
+
+	chain.genesisBlock = &ChainBlock{
+		height:     0,
+		block:      &block,
+		parent:     nil,
+		children:   nil,
+		valid:      true,
+		finalised:  true,
+		coordinate: coordinate,
+	}
+
+	chain.lastFinalisedBlock = chain.genesisBlock
+
+	chain.blocks = map[string]*ChainBlock{string(block.Hash): chain.genesisBlock}
+	chain.version = 1
+
+	chain.orphans.init(DefaultOrphanTimeout)
+}
+
+// Block tree changed, the canonical chain must be recalculated
+func (chain *Chain) changed() {
+	chain.version++
+}
+
+// Rebuild index of the blocks in the tree
+func (chain *Chain) index() {
+	chain.blocks = make(map[string]*ChainBlock)
+	chain.walk(func(chainBlock *ChainBlock) {
+		chain.blocks[string(chainBlock.block.Hash)] = chainBlock
+	})
+	chain.changed()
+}
+
+// Record invalidation and finalisation of blocks, nil disables recording.
+func (chain *Chain) SetRecorder(recorder Recorder) {
+	chain.recorder = recorder
+}
+
+// Prune history below the finalised block and all branches not descending from it, the block becomes the root.
+// Transactions since the last finalised block stay available to validate TXIn.
+func (chain *Chain) Prune(hash []byte) {
+
+	chainBlock := chain.Search(hash)
+	if chainBlock == nil || !chainBlock.finalised || chainBlock == chain.genesisBlock {
+		return
+	}
+
+	blocks := len(chain.blocks)
+	chainBlock.parent = nil
+	chain.genesisBlock = chainBlock
+	chain.index()
+	chain.pruned += blocks - len(chain.blocks)
+}
+
+// Copy of the block tree sharing the blocks, which do not change once inserted.
+// The copy is read and updated independently of the chain, without recorder.
+func (chain *Chain) Copy() *Chain {
+
+	copies := make(map[*ChainBlock]*ChainBlock, len(chain.blocks))
+	var copyBlock func(chainBlock *ChainBlock, parent *ChainBlock) *ChainBlock
+	copyBlock = func(chainBlock *ChainBlock, parent *ChainBlock) *ChainBlock {
+		copied := *chainBlock
+		copied.parent = parent
+		copied.children = make([]*ChainBlock, len(chainBlock.children))
+		copies[chainBlock] = &copied
+		for i, child := range chainBlock.children {
+			copied.children[i] = copyBlock(child, &copied)
+		}
+		return &copied
+	}
+
+	copied := &Chain{
+		genesisBlock: copyBlock(chain.genesisBlock, nil),
+		clock:        chain.clock,
+		blocks:       make(map[string]*ChainBlock, len(chain.blocks)),
+		version:      chain.version,
+		pruned:       chain.pruned,
+		rejected:     chain.rejected,
+	}
+	copied.lastFinalisedBlock = copies[chain.lastFinalisedBlock]
+	for hash, chainBlock := range chain.blocks {
+		copied.blocks[hash] = copies[chainBlock]
+	}
+
+	// Cached canonical head, unless it is no longer in the tree
+	if head, ok := copies[chain.head]; ok {
+		copied.head = head
+		copied.headVersion = chain.headVersion
+	}
+
+	copied.orphans.init(chain.orphans.timeout)
+	copied.orphans.stats = chain.orphans.stats
+	for parentHash, orphans := range chain.orphans.orphans {
+		copied.orphans.orphans[parentHash] = append([]*orphan(nil), orphans...)
+	}
+
+	return copied
+}
+
+// Number of blocks removed by pruning
+func (chain *Chain) Pruned() int {
+	return chain.pruned
+}
+
+// Insert block, a block whose parent has not arrived yet waits in the orphan pool.
+// A block already in the tree is ignored, a block failing verification is rejected.
+func (chain *Chain) Insert(block *Block) {
+
+	now := chain.clock.Now()
+	chain.orphans.expire(now)
+
+	if chain.Search(block.Hash) != nil {
+		return
+	}
+
+	if chain.verifier != nil {
+		if err := chain.verifier.VerifyBlock(block); err != nil {
+			chain.rejected++
+			return
+		}
+	}
+
+	parent := chain.Search(block.ParentHash)
+	if parent == nil {
+		chain.orphans.add(block, now)
+		return
+	}
+
+	chain.insert(parent, block)
+}
+
+// Insert block on top of parent, followed by the orphans waiting for it
+func (chain *Chain) insert(parent *ChainBlock, block *Block) {
+
+	// Verified blocks are signed for their slot, which must follow the slot of the parent
+	if chain.verifier != nil && block.Slot <= parent.block.Slot {
+		chain.rejected++
+		return
+	}
+
+	// Calculate X coordinate
+	x := chain.clock.Now().Seconds() * PixelsPerSecond
+
+	// Calculate Y coordinate
+	y := parent.coordinate.Y
+	if chain.BlockInLongestChain(parent) {
+		y = 0
+	}
+	nrOfChild := float32(len(parent.children))
+	if nrOfChild > 0 {
+		y +=  nrOfChild*20
+	}
+
+	coordinate := Coordinate{
+		X:      float32(x),
+		Y:      y,
+		Status: StatusStale,
+	}
+
+	chainBlock := ChainBlock{
+		height:     parent.height + 1,
+		block:      block,
+		parent:     parent,
+		children:   []*ChainBlock{},
+		valid:      true,
+		finalised:  false,
+		coordinate: coordinate,
+	}
+
+	parent.children = append(parent.children, &chainBlock)
+	chain.blocks[string(block.Hash)] = &chainBlock
+	chain.changed()
+
+	for _, orphan := range chain.orphans.adopt(block.Hash) {
+		chain.insert(&chainBlock, orphan)
+	}
+}
+
+// Finalise blocks
+func (chain *Chain) Finalise(hash []byte) {
+
+	chainBlock := chain.Search(hash)
+
+	if chainBlock != nil {
+		chain.finalise(chainBlock)
+		chain.lastFinalisedBlock = chainBlock
+		chain.changed()
+	}
+}
+
+func (chain *Chain) finalise(chainBlock *ChainBlock) {
+
+	// Ancestors of a finalised block are finalised
+	if chainBlock.finalised {
+		return
+	}
+
+	if chainBlock.parent != nil {
+		chain.finalise(chainBlock.parent)
+	}
+	chainBlock.finalised = true
+
+	if chain.recorder != nil {
+		chain.recorder.BlockFinalised(chainBlock.block)
+	}
+}
+
+// Search block or return nil
+func (chain *Chain) Search(hash []byte) *ChainBlock {
+	return chain.blocks[string(hash)]
+}
+
+// Count all blocks in the tree, excluding the genesis block, and the invalid ones among them.
+func (chain *Chain) CountBlocks() (int, int) {
+	blocks, invalid := countBlocks(chain.genesisBlock)
+	return blocks - 1, invalid
+}
+
+func countBlocks(chainBlock *ChainBlock) (int, int) {
+
+	blocks, invalid := 1, 0
+	if !chainBlock.valid {
+		invalid++
+	}
+
+	for _, child := range chainBlock.children {
+		childBlocks, childInvalid := countBlocks(child)
+		blocks += childBlocks
+		invalid += childInvalid
+	}
+
+	return blocks, invalid
+}
+
+// Visit every block in the tree, parents before children.
+func (chain *Chain) walk(visit func(chainBlock *ChainBlock)) {
+	walk(chain.genesisBlock, visit)
+}
+
+func walk(chainBlock *ChainBlock, visit func(chainBlock *ChainBlock)) {
+	visit(chainBlock)
+	for _, child := range chainBlock.children {
+		walk(child, visit)
+	}
+}
+
+func (chain *Chain) GenesisBlock() *ChainBlock {
+	return chain.genesisBlock
+}
+
+func (chain *Chain) LastFinalisedBlock() *ChainBlock {
+	return chain.lastFinalisedBlock
+}
+
+// Get TX Out list since last finalised block, uptil lastChainBLock.
+func (chain *Chain) GetTXOutList(lastBlockHash []byte) []*Transaction {
+	chainBlock := chain.Search(lastBlockHash)
+	return chain.getTXOutList(chainBlock)
+}
+
+// Get TX Out list since last finalised block, uptil lastChainBLock.
+func (chain *Chain) getTXOutList(lastChainBlock *ChainBlock) []*Transaction {
+
+	txOutList := make([]*Transaction, 0)
+	if !lastChainBlock.finalised {
+		// Fresh list, appending to the transactions of the block would share its backing array
+		txOutList = append(txOutList, lastChainBlock.block.TXOut...)
+		txOutList = append(txOutList, chain.getTXOutList(lastChainBlock.parent)...)
+	}
+	return txOutList
+}
+
+// Get TX Out list of longest chain since last finalised block.
+func (chain *Chain) GetLongestChainTXOutList() []*Transaction {
+	return chain.getTXOutList(chain.canonicalHead())
+}
+
+// Get TX Out list since last finalised block, uptil lastChainBLock.
+func (chain *Chain) GetTXInList(lastBlockHash []byte) []*Transaction {
+	chainBlock := chain.Search(lastBlockHash)
+	return chain.getTXInList(chainBlock)
+}
+
+// Get TX Out list since last finalised block, uptil lastChainBLock.
+func (chain *Chain) getTXInList(lastChainBlock *ChainBlock) []*Transaction {
+
+	txInList := make([]*Transaction, 0)
+	if !lastChainBlock.finalised {
+		// Fresh list, appending to the transactions of the block would share its backing array
+		txInList = append(txInList, lastChainBlock.block.TXIn...)
+		txInList = append(txInList, chain.getTXInList(lastChainBlock.parent)...)
+	}
+	return txInList
+}
+
+// Get longest three chains
+func (chain *Chain) GetLongestChains(numberOfChains int, validOnly bool) []*ChainBlock {
+	return chain.lastFinalisedBlock.getLongestChains(numberOfChains, validOnly)
+}
+
+// Get longest chains (array of numberOfChain blocks)
+func (chainBlock *ChainBlock) getLongestChains(numberOfChains int, validOnly bool) []*ChainBlock {
+
+	longestChains := make([]*ChainBlock, numberOfChains)
+
+	for i := 0; i < numberOfChains; i++ {
+		longestChains[i] = chainBlock;
+	}
+
+	for _, child := range chainBlock.children {
+
+		childChains := child.getLongestChains(numberOfChains, validOnly)
+
+		for i := 0; i < numberOfChains; i++ {
+			if longestChains[i].height < childChains[0].height {
+				if !validOnly || childChains[0].valid {
+					longestChains[i] = childChains[0]
+					break;
+				}
+			}
+		}
+
+		for j := 1; j < numberOfChains; j++ {
+			if !reflect.DeepEqual(childChains[j].block.Hash, childChains[j-1].block.Hash) {
+				for i := 0; i < numberOfChains; i++ {
+					if longestChains[i].height < childChains[j].height {
+						if !validOnly || childChains[j].valid {
+							longestChains[i] = childChains[j]
+							break;
+						}
+					}
+				}
+			}
+		}
+	}
+	return longestChains
+}
+
+func (chain *Chain) UpdateConsistency(txOutList []*Transaction) {
+	chain.lastFinalisedBlock.updateConsistency(NewTxSet(txOutList), chain.recorder)
+	chain.changed()
+}
+
+func (chain *Chain) PrettyPrint() {
+	chain.prettyPrint("", chain.genesisBlock, true)
+}
+
+// Retrieve whether block is part of longest chain.
+func (chain *Chain) BlockInLongestChain(chainBlock *ChainBlock) bool {
+	chain.canonicalHead()
+	return chainBlock.canonical == chain.version
+}
+
+// Head of the longest valid chain, its ancestors are marked canonical.
+func (chain *Chain) canonicalHead() *ChainBlock {
+
+	if chain.headVersion != chain.version {
+		chain.head = chain.GetLongestChains(1, true)[0]
+		for chainBlock := chain.head; chainBlock != nil; chainBlock = chainBlock.parent {
+			chainBlock.canonical = chain.version
+		}
+		chain.headVersion = chain.version
+	}
+	return chain.head
+}
+
+func (chain *Chain) UpdateVisualisation(isShardChain bool) {
+	chain.updateVisualisation(isShardChain, chain.genesisBlock)
+}
+
+func (chain *Chain) updateVisualisation(isShardChain bool, chainBlock *ChainBlock) {
+
+	if isShardChain {
+
+		if chainBlock == chain.genesisBlock {
+			chainBlock.coordinate.Status = StatusGenesis
+		} else if chainBlock.finalised {
+			chainBlock.coordinate.Status = StatusFinalised
+		} else if !chainBlock.valid {
+			chainBlock.coordinate.Status = StatusInvalid
+		} else if chain.BlockInLongestChain(chainBlock) {
+			chainBlock.coordinate.Status = StatusCanonical
+		} else {
+			chainBlock.coordinate.Status = StatusStale
+		}
+
+	} else {
+
+		if chainBlock == chain.lastFinalisedBlock {
+			chainBlock.coordinate.Status = StatusGenesis
+		} else if chainBlock.finalised {
+			chainBlock.coordinate.Status = StatusFinalisedOther
+		} else if chain.BlockInLongestChain(chainBlock) {
+			chainBlock.coordinate.Status = StatusCanonical
+		} else {
+			chainBlock.coordinate.Status = StatusPruned
+		}
+	}
+
+	for _, child := range chainBlock.children {
+		chain.updateVisualisation(isShardChain,child)
+	}
+}
+
+func (chain *Chain) prettyPrint(prefix string, chainBlock *ChainBlock, lastChild bool) {
+
+	fmt.Printf(prefix)
+
+	if lastChild {
+		fmt.Print("└──")
+	} else {
+		fmt.Print("├──")
+	}
+
+	output := "(" + base64.URLEncoding.EncodeToString(chainBlock.block.Hash) + ")"
+	for _, txIn := range chainBlock.block.TXIn {
+		output = output + " TXin: " + strconv.Itoa(txIn.TargetShard) + ","
+	}
+	for _, txOut := range chainBlock.block.TXOut {
+		output = output + " TXout: " + strconv.Itoa(txOut.TargetShard) + ","
+	}
+
+	// Print colored block
+	if chainBlock.finalised {
+		color.Green(output)
+	} else if !chainBlock.valid {
+		color.Red(output)
+	} else if chain.BlockInLongestChain(chainBlock) {
+		color.Yellow(output)
+	} else {
+		color.White(output)
+	}
+
+	for i, child := range chainBlock.children {
+
+		addPrefix := "│   "
+		if lastChild {
+			addPrefix = "    "
+		}
+
+		if i == len(chainBlock.children)-1 {
+			chain.prettyPrint(prefix+addPrefix, child, true);
+		} else {
+			chain.prettyPrint(prefix+addPrefix, child, false);
+		}
+	}
+}
+
+func (chain *Chain) GetChainBlock(txOUt *Transaction)[]*ChainBlock {
+	return chain.getChainBlock(txOUt,chain.genesisBlock)
+}
+
+func (chain *Chain) getChainBlock(txOUt *Transaction, chainBlock *ChainBlock) []*ChainBlock{
+
+	blocks := []*ChainBlock{}
+
+	for _, tx := range chainBlock.block.TXOut {
+		if tx.Hash == txOUt.Hash  {
+			blocks = append(blocks, chainBlock)
+		}
+	}
+
+	for _, child := range chainBlock.children{
+		blocks = append(blocks, chain.getChainBlock(txOUt,child)...)
+	}
+
+	return blocks
+}
+
+
+func rightPad2Len(s string, padStr string, overallLen int) string {
+	var padCountInt int
+	padCountInt = 1 + ((overallLen - len(padStr)) / len(padStr))
+	var retStr = s + strings.Repeat(padStr, padCountInt)
+	return retStr[:overallLen]
+}
@@ -0,0 +1,331 @@
+package chain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+// Create chain of shard driven by a discrete-event clock.
+func newTestChain(shard int) *Chain {
+	chain := &Chain{}
+	chain.Init(shard, clock.NewEventClock())
+	return chain
+}
+
+// Insert block with transactions on top of parent and return it.
+func insertTestBlock(t *testing.T, chain *Chain, parent *ChainBlock, txIn []*Transaction, txOut []*Transaction) *ChainBlock {
+	t.Helper()
+
+	block := Block{
+		Shard:      chain.genesisBlock.block.Shard,
+		ParentHash: parent.block.Hash,
+		Slot:       parent.height + 1,
+		TXIn:       txIn,
+		TXOut:      txOut,
+		Validator:  fmt.Sprintf("block %d-%d", parent.height+1, len(parent.children)),
+	}
+	block.SetHash()
+
+	chain.Insert(&block)
+
+	chainBlock := chain.Search(block.Hash)
+	if chainBlock == nil {
+		t.Fatalf("inserted block %x not found", block.Hash)
+	}
+	return chainBlock
+}
+
+// Create transaction with unique hash.
+func newTestTransaction(source int, target int, data string) *Transaction {
+	tx := Transaction{
+		SourceShard: source,
+		TargetShard: target,
+		Data:        data,
+	}
+	tx.SetHash()
+	return &tx
+}
+
+func TestChainInsert(t *testing.T) {
+
+	chain := newTestChain(1)
+	genesis := chain.genesisBlock
+
+	a := insertTestBlock(t, chain, genesis, nil, nil)
+	b := insertTestBlock(t, chain, a, nil, nil)
+	c := insertTestBlock(t, chain, a, nil, nil)
+
+	if a.parent != genesis || b.parent != a || c.parent != a {
+		t.Errorf("unexpected parents")
+	}
+	if len(a.children) != 2 {
+		t.Errorf("expected 2 children, got %d", len(a.children))
+	}
+	if b.height != 2 || c.height != 2 {
+		t.Errorf("expected height 2, got %d and %d", b.height, c.height)
+	}
+	if !b.valid || b.finalised {
+		t.Errorf("new block must be valid and not finalised")
+	}
+	if blocks, invalid := chain.CountBlocks(); blocks != 3 || invalid != 0 {
+		t.Errorf("expected 3 blocks and 0 invalid, got %d and %d", blocks, invalid)
+	}
+	if chain.Search([]byte("unknown")) != nil {
+		t.Errorf("search of unknown hash must return nil")
+	}
+}
+
+func TestGetLongestChains(t *testing.T) {
+
+	// genesis - a - b - c
+	//             \ d
+	//  \ e
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	b := insertTestBlock(t, chain, a, nil, nil)
+	c := insertTestBlock(t, chain, b, nil, nil)
+	d := insertTestBlock(t, chain, a, nil, nil)
+	e := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+
+	longest := chain.GetLongestChains(3, true)
+
+	if longest[0] != c {
+		t.Errorf("expected c as longest chain, got height %d", longest[0].height)
+	}
+
+	// Candidates are distinct and ordered by height, e is shorter than all other candidates
+	for i := 1; i < len(longest); i++ {
+		if longest[i].height > longest[i-1].height {
+			t.Errorf("candidates not ordered by height: %d before %d", longest[i-1].height, longest[i].height)
+		}
+		for j := 0; j < i; j++ {
+			if longest[i] == longest[j] {
+				t.Errorf("candidate at height %d returned twice", longest[i].height)
+			}
+		}
+		if longest[i] == e {
+			t.Errorf("e must not be a candidate")
+		}
+	}
+	if longest[1] != d && longest[2] != d {
+		t.Errorf("expected fork d as candidate")
+	}
+}
+
+func TestGetLongestChainsValidOnly(t *testing.T) {
+
+	// genesis - a - b - c (invalid)
+	//                \ d
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	b := insertTestBlock(t, chain, a, nil, nil)
+	c := insertTestBlock(t, chain, b, nil, nil)
+	d := insertTestBlock(t, chain, b, nil, nil)
+
+	c.invalidateBlock(nil)
+
+	for _, longest := range chain.GetLongestChains(3, true) {
+		if !longest.valid {
+			t.Errorf("valid only returned invalid block at height %d", longest.height)
+		}
+	}
+	if longest := chain.GetLongestChains(1, true)[0]; longest != d {
+		t.Errorf("expected d as longest valid chain, got height %d", longest.height)
+	}
+	if longest := chain.GetLongestChains(1, false)[0]; longest.height != 3 {
+		t.Errorf("expected longest chain of height 3, got %d", longest.height)
+	}
+}
+
+func TestChainFinalise(t *testing.T) {
+
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	b := insertTestBlock(t, chain, a, nil, nil)
+	c := insertTestBlock(t, chain, b, nil, nil)
+	stale := insertTestBlock(t, chain, a, nil, nil)
+
+	chain.Finalise(b.block.Hash)
+
+	if !a.finalised || !b.finalised {
+		t.Errorf("block and its ancestors must be finalised")
+	}
+	if c.finalised || stale.finalised {
+		t.Errorf("descendants and stale blocks must not be finalised")
+	}
+	if chain.lastFinalisedBlock != b {
+		t.Errorf("expected b as last finalised block")
+	}
+
+	// Unknown blocks are ignored
+	chain.Finalise([]byte("unknown"))
+	if chain.lastFinalisedBlock != b {
+		t.Errorf("finalising unknown block changed last finalised block")
+	}
+}
+
+func TestGetTXLists(t *testing.T) {
+
+	out1 := newTestTransaction(1, 2, "out1")
+	out2 := newTestTransaction(1, 3, "out2")
+	out3 := newTestTransaction(1, 2, "out3")
+	in1 := newTestTransaction(2, 1, "in1")
+
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, nil, []*Transaction{out1})
+	b := insertTestBlock(t, chain, a, []*Transaction{in1}, []*Transaction{out2})
+	c := insertTestBlock(t, chain, b, nil, []*Transaction{out3})
+
+	if txOut := chain.GetTXOutList(c.block.Hash); len(txOut) != 3 {
+		t.Errorf("expected 3 TX out, got %d", len(txOut))
+	}
+	if txIn := chain.GetTXInList(c.block.Hash); len(txIn) != 1 || txIn[0] != in1 {
+		t.Errorf("expected TX in in1, got %v", txIn)
+	}
+
+	// Only transactions since the last finalised block
+	chain.Finalise(a.block.Hash)
+	txOut := chain.GetTXOutList(c.block.Hash)
+	if len(txOut) != 2 || ContainsTx(out1, &txOut) {
+		t.Errorf("expected TX out since finalised block, got %d", len(txOut))
+	}
+	if longestTxOut := chain.GetLongestChainTXOutList(); len(longestTxOut) != 2 {
+		t.Errorf("expected 2 TX out in longest chain, got %d", len(longestTxOut))
+	}
+}
+
+func TestUpdateConsistency(t *testing.T) {
+
+	tx1 := newTestTransaction(2, 1, "tx1")
+	tx2 := newTestTransaction(2, 1, "tx2")
+
+	// genesis - a (tx1) - b (tx2) - c
+	//                   \ d (tx1)
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, []*Transaction{tx1}, nil)
+	b := insertTestBlock(t, chain, a, []*Transaction{tx2}, nil)
+	c := insertTestBlock(t, chain, b, nil, nil)
+	d := insertTestBlock(t, chain, a, []*Transaction{tx1}, nil)
+
+	// tx2 not available, tx1 only once
+	chain.UpdateConsistency([]*Transaction{tx1})
+
+	if !a.valid {
+		t.Errorf("a includes available transaction and must be valid")
+	}
+	if b.valid || c.valid {
+		t.Errorf("b includes unavailable transaction, b and its child must be invalid")
+	}
+	if d.valid {
+		t.Errorf("d processes tx1 twice and must be invalid")
+	}
+
+	// Transactions become available, blocks are re-validated
+	chain.UpdateConsistency([]*Transaction{tx1, tx2})
+	if !b.valid || !c.valid {
+		t.Errorf("b and c must be valid once tx2 is available")
+	}
+
+	// Finalised blocks are never invalidated
+	chain.Finalise(b.block.Hash)
+	chain.UpdateConsistency([]*Transaction{})
+	if !a.valid || !b.valid {
+		t.Errorf("finalised blocks must stay valid")
+	}
+}
+
+func TestInsertOrphan(t *testing.T) {
+
+	eventClock := clock.NewEventClock()
+	chain := &Chain{}
+	chain.Init(1, eventClock)
+	chain.SetOrphanTimeout(10 * time.Second)
+
+	newBlock := func(parentHash []byte, name string) *Block {
+		block := &Block{Shard: 1, ParentHash: parentHash, Validator: name}
+		block.SetHash()
+		return block
+	}
+
+	a := newBlock(chain.genesisBlock.block.Hash, "a")
+	b := newBlock(a.Hash, "b")
+	c := newBlock(b.Hash, "c")
+
+	// Children arrive before their parent
+	chain.Insert(c)
+	chain.Insert(b)
+	chain.Insert(b)
+	if chain.Search(b.Hash) != nil || chain.Search(c.Hash) != nil {
+		t.Fatalf("orphans inserted before their parent")
+	}
+	if stats := chain.OrphanStats(); stats.Received != 2 || stats.Pending != 2 {
+		t.Errorf("expected 2 orphans received and pending, got %+v", stats)
+	}
+
+	chain.Insert(a)
+	chainBlock := chain.Search(c.Hash)
+	if chainBlock == nil || chainBlock.height != 3 || chainBlock.parent.block != b {
+		t.Fatalf("orphans not inserted after their parent")
+	}
+	if stats := chain.OrphanStats(); stats.Adopted != 2 || stats.Pending != 0 {
+		t.Errorf("expected 2 orphans adopted and none pending, got %+v", stats)
+	}
+
+	// Orphans expire after the timeout
+	d := newBlock([]byte("unknown"), "d")
+	chain.Insert(d)
+	eventClock.Advance(10 * time.Second)
+	chain.Insert(newBlock(c.Hash, "e"))
+
+	if stats := chain.OrphanStats(); stats.Expired != 1 || stats.Pending != 0 {
+		t.Errorf("expected 1 orphan expired and none pending, got %+v", stats)
+	}
+}
+
+func TestChainCopy(t *testing.T) {
+
+	tx := newTestTransaction(2, 1, "tx")
+
+	// genesis - a - b
+	//             \ c (tx) invalid
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	b := insertTestBlock(t, chain, a, nil, nil)
+	c := insertTestBlock(t, chain, a, []*Transaction{tx}, nil)
+	chain.Finalise(a.block.Hash)
+	chain.UpdateConsistency(nil)
+
+	copied := chain.Copy()
+
+	for _, original := range []*ChainBlock{chain.genesisBlock, a, b, c} {
+		chainBlock := copied.Search(original.block.Hash)
+		if chainBlock == nil || chainBlock == original {
+			t.Fatalf("block at height %d not copied", original.height)
+		}
+		if chainBlock.height != original.height || chainBlock.valid != original.valid ||
+			chainBlock.finalised != original.finalised || len(chainBlock.children) != len(original.children) {
+			t.Errorf("copy of block at height %d differs", original.height)
+		}
+		if original.parent != nil && chainBlock.parent != copied.Search(original.parent.block.Hash) {
+			t.Errorf("parent of block at height %d not copied", original.height)
+		}
+	}
+	if copied.LastFinalisedBlock() != copied.Search(a.block.Hash) {
+		t.Errorf("last finalised block not copied")
+	}
+	if !copied.BlockInLongestChain(copied.Search(b.block.Hash)) {
+		t.Errorf("expected b in the longest chain of the copy")
+	}
+
+	// Changes of the chain do not affect the copy
+	d := insertTestBlock(t, chain, b, nil, nil)
+	if copied.Search(d.block.Hash) != nil || len(copied.Search(b.block.Hash).children) != 0 {
+		t.Errorf("block inserted in the chain appears in the copy")
+	}
+	if copied.GetLongestChains(1, true)[0].block != b.block {
+		t.Errorf("expected b as head of the copy")
+	}
+}
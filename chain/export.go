@@ -0,0 +1,195 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DAG of the block trees of all shards, linked by cross-shard transactions.
+type DAG struct {
+	Blocks []DAGBlock `json:"blocks"`
+	Edges  []DAGEdge  `json:"crossShardEdges"`
+}
+
+// Block of the DAG, hashes are hex encoded.
+type DAGBlock struct {
+	Hash      string   `json:"hash"`
+	Parent    string   `json:"parent,omitempty"`
+	Shard     int      `json:"shard"`
+	Height    int      `json:"height"`
+	Validator string   `json:"validator,omitempty"`
+	Valid     bool     `json:"valid"`
+	Finalised bool     `json:"finalised"`
+	Canonical bool     `json:"canonical"`
+	TXIn      []string `json:"txIn"`
+	TXOut     []string `json:"txOut"`
+}
+
+// Cross-shard edge from the block including the TXOut to the block including the TXIn.
+type DAGEdge struct {
+	TX          string `json:"tx"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	SourceShard int    `json:"sourceShard"`
+	TargetShard int    `json:"targetShard"`
+}
+
+// Build the DAG from the block tree of every shard (index shard, nil entries are skipped).
+func NewDAG(chains []*Chain) DAG {
+
+	dag := DAG{
+		Blocks: make([]DAGBlock, 0),
+		Edges:  make([]DAGEdge, 0),
+	}
+
+	// Blocks including every TXOut
+	txOutBlocks := make(map[string][]*ChainBlock)
+
+	for _, chain := range chains {
+		if chain == nil {
+			continue
+		}
+
+		canonical := make(map[*ChainBlock]bool)
+		for chainBlock := chain.GetLongestChains(1, true)[0]; chainBlock != nil; chainBlock = chainBlock.parent {
+			canonical[chainBlock] = true
+		}
+
+		chain.walk(func(chainBlock *ChainBlock) {
+
+			block := DAGBlock{
+				Hash:      fmt.Sprintf("%x", chainBlock.block.Hash),
+				Shard:     chainBlock.block.Shard,
+				Height:    chainBlock.height,
+				Validator: chainBlock.block.Validator,
+				Valid:     chainBlock.valid,
+				Finalised: chainBlock.finalised,
+				Canonical: canonical[chainBlock],
+				TXIn:      make([]string, 0, len(chainBlock.block.TXIn)),
+				TXOut:     make([]string, 0, len(chainBlock.block.TXOut)),
+			}
+			if chainBlock.parent != nil {
+				block.Parent = fmt.Sprintf("%x", chainBlock.parent.block.Hash)
+			}
+			for _, tx := range chainBlock.block.TXIn {
+				block.TXIn = append(block.TXIn, fmt.Sprintf("%x", tx.Hash))
+			}
+			for _, tx := range chainBlock.block.TXOut {
+				block.TXOut = append(block.TXOut, fmt.Sprintf("%x", tx.Hash))
+				txOutBlocks[tx.Hash] = append(txOutBlocks[tx.Hash], chainBlock)
+			}
+
+			dag.Blocks = append(dag.Blocks, block)
+		})
+	}
+
+	// Link every TXIn to the blocks including the TXOut
+	for _, chain := range chains {
+		if chain == nil {
+			continue
+		}
+
+		chain.walk(func(chainBlock *ChainBlock) {
+			for _, tx := range chainBlock.block.TXIn {
+				for _, source := range txOutBlocks[tx.Hash] {
+					dag.Edges = append(dag.Edges, DAGEdge{
+						TX:          fmt.Sprintf("%x", tx.Hash),
+						From:        fmt.Sprintf("%x", source.block.Hash),
+						To:          fmt.Sprintf("%x", chainBlock.block.Hash),
+						SourceShard: tx.SourceShard,
+						TargetShard: tx.TargetShard,
+					})
+				}
+			}
+		})
+	}
+
+	return dag
+}
+
+// Write DAG as indented JSON.
+func (dag *DAG) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dag)
+}
+
+// Write DAG as Graphviz DOT, one cluster per shard and dashed cross-shard edges.
+func (dag *DAG) WriteDOT(w io.Writer) error {
+
+	// Group blocks per shard, keeping the order of the blocks
+	shards := make([]int, 0)
+	blocks := make(map[int][]DAGBlock)
+	for _, block := range dag.Blocks {
+		if _, ok := blocks[block.Shard]; !ok {
+			shards = append(shards, block.Shard)
+		}
+		blocks[block.Shard] = append(blocks[block.Shard], block)
+	}
+
+	out := &dotWriter{w: w}
+
+	out.printf("digraph dag {\n")
+	out.printf("\trankdir=LR;\n")
+	out.printf("\tnode [shape=box, style=filled, fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, shard := range shards {
+		out.printf("\tsubgraph cluster_shard_%d {\n", shard)
+		out.printf("\t\tlabel=\"Shard %d\";\n", shard)
+
+		for _, block := range blocks[shard] {
+			out.printf("\t\t\"%s\" [label=\"%d\\n%s\", fillcolor=\"%s\"];\n",
+				block.Hash, block.Height, shortHash(block.Hash), block.dotColor())
+		}
+		for _, block := range blocks[shard] {
+			if block.Parent != "" {
+				out.printf("\t\t\"%s\" -> \"%s\";\n", block.Parent, block.Hash)
+			}
+		}
+
+		out.printf("\t}\n")
+	}
+
+	for _, edge := range dag.Edges {
+		out.printf("\t\"%s\" -> \"%s\" [style=dashed, color=\"#5f5f5f\", constraint=false, tooltip=\"%s\"];\n",
+			edge.From, edge.To, shortHash(edge.TX))
+	}
+
+	out.printf("}\n")
+
+	return out.err
+}
+
+// Colour of the block, matching the visualiser
+func (block *DAGBlock) dotColor() string {
+	if block.Parent == "" {
+		return "#00ccff"
+	} else if block.Finalised {
+		return "#ccff00"
+	} else if !block.Valid {
+		return "#ff3300"
+	} else if block.Canonical {
+		return "#f3f315"
+	}
+	return "#c20ed5"
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+// Writer keeping the first error, so DOT output is written without checking every line.
+type dotWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (out *dotWriter) printf(format string, a ...interface{}) {
+	if out.err == nil {
+		_, out.err = fmt.Fprintf(out.w, format, a...)
+	}
+}
@@ -0,0 +1,88 @@
+package chain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewDAG(t *testing.T) {
+
+	tx := newTestTransaction(1, 2, "cross-shard")
+
+	source := newTestChain(1)
+	out := insertTestBlock(t, source, source.genesisBlock, nil, []*Transaction{tx})
+	insertTestBlock(t, source, source.genesisBlock, nil, nil)
+
+	target := newTestChain(2)
+	in := insertTestBlock(t, target, target.genesisBlock, []*Transaction{tx}, nil)
+	inStale := insertTestBlock(t, target, target.genesisBlock, nil, nil)
+	target.Finalise(in.block.Hash)
+
+	dag := NewDAG([]*Chain{nil, source, target})
+
+	if len(dag.Blocks) != 6 {
+		t.Fatalf("expected 6 blocks, got %d", len(dag.Blocks))
+	}
+
+	blocks := make(map[string]DAGBlock)
+	for _, block := range dag.Blocks {
+		blocks[block.Hash] = block
+	}
+
+	inBlock := blocks[fmt.Sprintf("%x", in.block.Hash)]
+	if !inBlock.Finalised || !inBlock.Canonical || !inBlock.Valid || inBlock.Height != 1 {
+		t.Errorf("unexpected flags of finalised block: %+v", inBlock)
+	}
+	if inBlock.Parent != fmt.Sprintf("%x", target.genesisBlock.block.Hash) {
+		t.Errorf("unexpected parent %s", inBlock.Parent)
+	}
+	if stale := blocks[fmt.Sprintf("%x", inStale.block.Hash)]; stale.Canonical || stale.Finalised {
+		t.Errorf("unexpected flags of stale block: %+v", stale)
+	}
+
+	if len(dag.Edges) != 1 {
+		t.Fatalf("expected 1 cross-shard edge, got %d", len(dag.Edges))
+	}
+	edge := dag.Edges[0]
+	if edge.From != fmt.Sprintf("%x", out.block.Hash) || edge.To != inBlock.Hash || edge.SourceShard != 1 || edge.TargetShard != 2 {
+		t.Errorf("unexpected edge %+v", edge)
+	}
+}
+
+func TestDAGWriters(t *testing.T) {
+
+	tx := newTestTransaction(1, 2, "cross-shard")
+
+	source := newTestChain(1)
+	out := insertTestBlock(t, source, source.genesisBlock, nil, []*Transaction{tx})
+	target := newTestChain(2)
+	in := insertTestBlock(t, target, target.genesisBlock, []*Transaction{tx}, nil)
+
+	dag := NewDAG([]*Chain{nil, source, target})
+
+	var dot bytes.Buffer
+	if err := dag.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	edge := fmt.Sprintf("\"%x\" -> \"%x\" [style=dashed", out.block.Hash, in.block.Hash)
+	for _, expected := range []string{"digraph dag {", "subgraph cluster_shard_1 {", "subgraph cluster_shard_2 {", edge} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("DOT output misses %q", expected)
+		}
+	}
+
+	var encoded bytes.Buffer
+	if err := dag.WriteJSON(&encoded); err != nil {
+		t.Fatal(err)
+	}
+	var decoded DAG
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Blocks) != len(dag.Blocks) || len(decoded.Edges) != len(dag.Edges) {
+		t.Errorf("JSON round trip changed the DAG")
+	}
+}
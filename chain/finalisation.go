@@ -0,0 +1,135 @@
+package chain
+
+// Finalisation proposed by the beacon: last finalised block of every shard and the
+// finalised cross-shard transactions which are not processed yet, with the votes of the beacon committee.
+type Finalisation struct {
+	Height         int            `json:"height"`
+	Blocks         []Block        `json:"blocks"`
+	InconsistentTX []*Transaction `json:"inconsistentTX"`
+	Penalties      []int64        `json:"penalties"`
+	Votes          []Vote         `json:"votes,omitempty"`
+}
+
+type ShardFinalisation struct {
+	shard               int
+	newFinalisedBlock   *ChainBlock
+	canonicalChainBlock *ChainBlock
+	TXIn                *TxSet
+	TXOut               *TxSet
+}
+
+// Calculate next finalisation from the chains (index shard) and the previous finalisation.
+func CalculateFinalisation(chains []Chain, previous *Finalisation) Finalisation {
+
+	shardCount := len(chains) - 1
+
+	shardFinalisations := make([]ShardFinalisation, shardCount)
+
+	// Group inconsistent transactions by source shard
+	inconsistentTXOut := make([][]*Transaction, shardCount)
+	for _, tx := range previous.InconsistentTX {
+		inconsistentTXOut[tx.SourceShard-1] = append(inconsistentTXOut[tx.SourceShard-1], tx)
+	}
+
+	// Prepare finalisation object for each shard, with its inconsistent transactions
+	for i := 0; i < shardCount; i++ {
+		shard := i + 1
+		shardFinalisations[i] = ShardFinalisation{
+			shard:               shard,
+			canonicalChainBlock: chains[shard].GetLongestChains(1, false)[0],
+			newFinalisedBlock:   chains[shard].lastFinalisedBlock,
+			TXOut:               NewTxSet(inconsistentTXOut[i]),
+			TXIn:                NewTxSet(nil),
+		}
+	}
+
+	// Loop over all finalisation objects to include a new finalisation block until no block can be added anymore.
+	running := true
+
+	for running {
+
+		running = false
+
+		for shardIndex := 0; shardIndex < len(shardFinalisations); shardIndex++ {
+			if tryFinaliseNextBlock(shardIndex, &shardFinalisations) {
+				running = true
+			}
+		}
+	}
+
+	// New finalisation
+	inconsistentTX := make([]*Transaction, 0)
+	blocks := make([]Block, 0)
+
+	for _, finalisation := range shardFinalisations {
+		inconsistentTX = append(inconsistentTX, finalisation.TXOut.List()...)
+		blocks = append(blocks, *finalisation.newFinalisedBlock.block)
+	}
+
+	return Finalisation{
+		Height:         previous.Height + 1,
+		InconsistentTX: inconsistentTX,
+		Blocks:         blocks,
+	}
+}
+
+func tryFinaliseNextBlock(shardIndex int,  finalisations *[]ShardFinalisation) bool {
+
+	finalisation := (*finalisations)[shardIndex]
+
+	// Verify is next block is valid
+	nextBlock := finalisation.getNextBlock()
+
+	// Return false is there is no next block
+	if nextBlock == finalisation.newFinalisedBlock {
+		return false
+	}
+
+	// Verify if TXin are valid, a TXin included twice needs two TXout
+	for i, txIn := range nextBlock.block.TXIn {
+		if txIn.TargetShard != finalisation.shard || txIn.SourceShard < 1 || txIn.SourceShard > len(*finalisations) {
+			return false
+		}
+		spent := 1
+		for _, earlier := range nextBlock.block.TXIn[:i] {
+			if earlier.Hash == txIn.Hash {
+				spent++
+			}
+		}
+		if (*finalisations)[txIn.SourceShard-1].TXOut.Count(txIn) < spent {
+			return false
+		}
+	}
+
+	// Block is valid, update shardFinalisation
+	finalisation.newFinalisedBlock = nextBlock
+
+	// Remove TXin from other finalisations
+	for _, txIn := range nextBlock.block.TXIn {
+		(*finalisations)[txIn.SourceShard-1].TXOut.Remove(txIn)
+	}
+
+	// Add txOUT to TX out list
+	for _, txOut := range nextBlock.block.TXOut {
+		finalisation.TXOut.Add(txOut)
+	}
+
+	(*finalisations)[shardIndex] = finalisation
+
+	return true
+}
+
+// Reverse order find next canonical chain block
+func (finalisation *ShardFinalisation) getNextBlock() *ChainBlock {
+
+	nextBlock := finalisation.canonicalChainBlock
+
+	for nextBlock != finalisation.newFinalisedBlock {
+
+		if nextBlock.parent == finalisation.newFinalisedBlock {
+			break;
+		}
+		nextBlock = nextBlock.parent
+	}
+	return nextBlock
+}
@@ -0,0 +1,232 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+// Create chains of beacon (index 0, unused) and shards.
+func newTestChains(shardCount int) []Chain {
+	eventClock := clock.NewEventClock()
+	chains := make([]Chain, shardCount+1)
+	for i := range chains {
+		chains[i].Init(i, eventClock)
+	}
+	return chains
+}
+
+func emptyFinalisation() *Finalisation {
+	return &Finalisation{InconsistentTX: make([]*Transaction, 0)}
+}
+
+func TestCalculateFinalisationCrossShard(t *testing.T) {
+
+	x := newTestTransaction(1, 2, "x")
+	z := newTestTransaction(2, 1, "z")
+
+	// Shard 1 processes z which shard 2 creates, shard 2 processes x which shard 1 creates
+	chains := newTestChains(2)
+	a1 := insertTestBlock(t, &chains[1], chains[1].genesisBlock, nil, []*Transaction{x})
+	b1 := insertTestBlock(t, &chains[1], a1, []*Transaction{z}, nil)
+	a2 := insertTestBlock(t, &chains[2], chains[2].genesisBlock, []*Transaction{x}, []*Transaction{z})
+
+	finalisation := CalculateFinalisation(chains, emptyFinalisation())
+
+	if finalisation.Height != 1 {
+		t.Errorf("expected height 1, got %d", finalisation.Height)
+	}
+	if string(finalisation.Blocks[0].Hash) != string(b1.block.Hash) {
+		t.Errorf("expected shard 1 finalised up to b1")
+	}
+	if string(finalisation.Blocks[1].Hash) != string(a2.block.Hash) {
+		t.Errorf("expected shard 2 finalised up to a2")
+	}
+	if len(finalisation.InconsistentTX) != 0 {
+		t.Errorf("expected no inconsistent TX, got %d", len(finalisation.InconsistentTX))
+	}
+}
+
+func TestCalculateFinalisationInconsistent(t *testing.T) {
+
+	x := newTestTransaction(1, 2, "x")
+	unknown := newTestTransaction(1, 2, "unknown")
+
+	// Shard 2 processes a transaction shard 1 never created
+	chains := newTestChains(2)
+	a1 := insertTestBlock(t, &chains[1], chains[1].genesisBlock, nil, []*Transaction{x})
+	insertTestBlock(t, &chains[2], chains[2].genesisBlock, []*Transaction{unknown}, nil)
+
+	finalisation := CalculateFinalisation(chains, emptyFinalisation())
+
+	if string(finalisation.Blocks[0].Hash) != string(a1.block.Hash) {
+		t.Errorf("expected shard 1 finalised up to a1")
+	}
+	if string(finalisation.Blocks[1].Hash) != string(chains[2].genesisBlock.block.Hash) {
+		t.Errorf("inconsistent block of shard 2 must not be finalised")
+	}
+	if len(finalisation.InconsistentTX) != 1 || finalisation.InconsistentTX[0] != x {
+		t.Errorf("expected unprocessed x as inconsistent TX, got %d", len(finalisation.InconsistentTX))
+	}
+}
+
+func TestCalculateFinalisationProcessesInconsistentTX(t *testing.T) {
+
+	x := newTestTransaction(1, 2, "x")
+
+	// x is finalised in shard 1 by an earlier finalisation
+	chains := newTestChains(2)
+	a2 := insertTestBlock(t, &chains[2], chains[2].genesisBlock, []*Transaction{x}, nil)
+
+	previous := emptyFinalisation()
+	previous.Height = 4
+	previous.InconsistentTX = append(previous.InconsistentTX, x)
+
+	finalisation := CalculateFinalisation(chains, previous)
+
+	if finalisation.Height != 5 {
+		t.Errorf("expected height 5, got %d", finalisation.Height)
+	}
+	if string(finalisation.Blocks[1].Hash) != string(a2.block.Hash) {
+		t.Errorf("expected shard 2 finalised up to a2")
+	}
+	if len(finalisation.InconsistentTX) != 0 {
+		t.Errorf("expected x to be processed, got %d inconsistent TX", len(finalisation.InconsistentTX))
+	}
+}
+
+func TestTryFinaliseNextBlock(t *testing.T) {
+
+	x := newTestTransaction(2, 1, "x")
+
+	chains := newTestChains(2)
+	a1 := insertTestBlock(t, &chains[1], chains[1].genesisBlock, []*Transaction{x}, nil)
+
+	txOut1, txOut2 := NewTxSet(nil), NewTxSet(nil)
+	finalisations := []ShardFinalisation{
+		{shard: 1, newFinalisedBlock: chains[1].genesisBlock, canonicalChainBlock: a1, TXOut: txOut1},
+		{shard: 2, newFinalisedBlock: chains[2].genesisBlock, canonicalChainBlock: chains[2].genesisBlock, TXOut: txOut2},
+	}
+
+	if tryFinaliseNextBlock(0, &finalisations) {
+		t.Errorf("a1 must not be finalised before x is finalised in shard 2")
+	}
+	if tryFinaliseNextBlock(1, &finalisations) {
+		t.Errorf("shard 2 has no next block")
+	}
+
+	txOut2.Add(x)
+	if !tryFinaliseNextBlock(0, &finalisations) {
+		t.Errorf("a1 must be finalised once x is finalised in shard 2")
+	}
+	if finalisations[0].newFinalisedBlock != a1 {
+		t.Errorf("expected a1 as new finalised block")
+	}
+	if finalisations[1].TXOut.Len() != 0 {
+		t.Errorf("processed x must be removed from TX out list of shard 2")
+	}
+}
+
+// Blocks of a byzantine shard 2 processing transactions they are not entitled to are
+// invalid in its own block tree, and never finalised by the beacon.
+func TestCalculateFinalisationByzantineTXIn(t *testing.T) {
+
+	x := newTestTransaction(1, 2, "x")
+	stale := newTestTransaction(1, 2, "stale")
+	phantom := newTestTransaction(1, 2, "phantom")
+	misdirected := newTestTransaction(1, 3, "misdirected")
+
+	tests := []struct {
+		name string
+		txIn [][]*Transaction
+	}{
+		{"non-existent TXout", [][]*Transaction{{phantom}}},
+		{"TXout of stale branch", [][]*Transaction{{stale}}},
+		{"TXout for another shard", [][]*Transaction{{misdirected}}},
+		{"TXin twice in block", [][]*Transaction{{x, x}}},
+		{"TXin in parent and child", [][]*Transaction{{x}, {x}}},
+	}
+
+	for _, test := range tests {
+
+		// genesis - a1 (x, misdirected) - b1 - c1
+		//         \ s1 (stale)
+		chains := newTestChains(3)
+		a1 := insertTestBlock(t, &chains[1], chains[1].genesisBlock, nil, []*Transaction{x, misdirected})
+		b1 := insertTestBlock(t, &chains[1], a1, nil, nil)
+		insertTestBlock(t, &chains[1], b1, nil, nil)
+		insertTestBlock(t, &chains[1], chains[1].genesisBlock, nil, []*Transaction{stale})
+
+		// Shard 2 builds its blocks on top of each other, the last one is byzantine
+		parent := chains[2].genesisBlock
+		blocks := make([]*ChainBlock, 0)
+		for _, txIn := range test.txIn {
+			parent = insertTestBlock(t, &chains[2], parent, txIn, nil)
+			blocks = append(blocks, parent)
+		}
+		byzantine := blocks[len(blocks)-1]
+		expected := byzantine.parent
+
+		// Shard 2 sees the TXout of the longest chain of shard 1
+		txOut := make([]*Transaction, 0)
+		for _, tx := range chains[1].GetLongestChainTXOutList() {
+			if tx.TargetShard == 2 {
+				txOut = append(txOut, tx)
+			}
+		}
+		chains[2].UpdateConsistency(txOut)
+		if byzantine.valid {
+			t.Errorf("%s: block must be invalid", test.name)
+		}
+		if !expected.valid {
+			t.Errorf("%s: parent of block must stay valid", test.name)
+		}
+
+		finalisation := CalculateFinalisation(chains, emptyFinalisation())
+		if string(finalisation.Blocks[1].Hash) != string(expected.block.Hash) {
+			t.Errorf("%s: shard 2 must be finalised up to the parent of the block", test.name)
+		}
+	}
+}
+
+// Sibling blocks may both process a transaction, finalisation picks one branch so it is processed once.
+func TestCalculateFinalisationDoubleSpend(t *testing.T) {
+
+	x := newTestTransaction(1, 2, "x")
+
+	// Shard 2: genesis - c (x)
+	//                  \ d (x) - e
+	chains := newTestChains(2)
+	insertTestBlock(t, &chains[1], chains[1].genesisBlock, nil, []*Transaction{x})
+	c := insertTestBlock(t, &chains[2], chains[2].genesisBlock, []*Transaction{x}, nil)
+	d := insertTestBlock(t, &chains[2], chains[2].genesisBlock, []*Transaction{x}, nil)
+	e := insertTestBlock(t, &chains[2], d, nil, nil)
+
+	chains[2].UpdateConsistency([]*Transaction{x})
+	if !c.valid || !d.valid {
+		t.Errorf("sibling blocks each process x once and must be valid")
+	}
+
+	finalisation := CalculateFinalisation(chains, emptyFinalisation())
+	if string(finalisation.Blocks[1].Hash) != string(e.block.Hash) {
+		t.Errorf("expected shard 2 finalised up to e")
+	}
+	if len(finalisation.InconsistentTX) != 0 {
+		t.Errorf("expected x to be processed, got %d inconsistent TX", len(finalisation.InconsistentTX))
+	}
+	for _, block := range finalisation.Blocks {
+		chains[block.Shard].Finalise(block.Hash)
+	}
+
+	// Branch of c grows longer after the finalisation, it can never be finalised
+	f := insertTestBlock(t, &chains[2], c, nil, nil)
+	insertTestBlock(t, &chains[2], f, nil, nil)
+
+	finalisation = CalculateFinalisation(chains, &finalisation)
+	if string(finalisation.Blocks[1].Hash) != string(e.block.Hash) {
+		t.Errorf("branch of c must not be finalised")
+	}
+	if c.finalised {
+		t.Errorf("c must not be finalised")
+	}
+}
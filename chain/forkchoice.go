@@ -0,0 +1,201 @@
+package chain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fork-choice rules selecting the block a shard builds its next block upon
+const (
+	LongestChain    = "longest-chain"
+	HeaviestSubtree = "heaviest-subtree"
+	LatestMessage   = "latest-message"
+	MostTXProcessed = "most-tx-processed"
+)
+
+// Names of all fork-choice rules
+var ForkChoiceRules = []string{LongestChain, HeaviestSubtree, LatestMessage, MostTXProcessed}
+
+// ForkChoice ranks the valid heads of a block tree above the last finalised block.
+type ForkChoice interface {
+	// Name of the rule
+	Name() string
+	// Best count blocks to build upon, best first. Blocks repeat if the tree has fewer blocks.
+	Candidates(chain *Chain, count int) []*ChainBlock
+}
+
+// Create fork-choice rule by name
+func NewForkChoice(name string) (ForkChoice, error) {
+	switch name {
+	case LongestChain:
+		return longestChain{}, nil
+	case HeaviestSubtree:
+		return heaviestSubtree{}, nil
+	case LatestMessage:
+		return latestMessage{}, nil
+	case MostTXProcessed:
+		return mostTXProcessed{}, nil
+	}
+	return nil, fmt.Errorf("unknown fork-choice rule %q, expected %s", name, strings.Join(ForkChoiceRules, ", "))
+}
+
+// Highest valid blocks
+type longestChain struct{}
+
+func (longestChain) Name() string {
+	return LongestChain
+}
+
+func (longestChain) Candidates(chain *Chain, count int) []*ChainBlock {
+	return chain.GetLongestChains(count, true)
+}
+
+// GHOST: follow the child with most valid blocks in its subtree
+type heaviestSubtree struct{}
+
+func (heaviestSubtree) Name() string {
+	return HeaviestSubtree
+}
+
+func (heaviestSubtree) Candidates(chain *Chain, count int) []*ChainBlock {
+
+	weights := make(map[*ChainBlock]int)
+	var weigh func(chainBlock *ChainBlock) int
+	weigh = func(chainBlock *ChainBlock) int {
+		weight := 1
+		for _, child := range chainBlock.children {
+			if child.valid {
+				weight += weigh(child)
+			}
+		}
+		weights[chainBlock] = weight
+		return weight
+	}
+	weigh(chain.lastFinalisedBlock)
+
+	return candidates(chain, ghostHeads(chain.lastFinalisedBlock, weights), count)
+}
+
+// LMD GHOST: follow the child with most validators whose latest block is in its subtree.
+// The latest block of a validator is its highest valid block, the first one on equal height.
+// Validators are the signing proposers of the blocks, every committee member proposes many blocks.
+type latestMessage struct{}
+
+func (latestMessage) Name() string {
+	return LatestMessage
+}
+
+func (latestMessage) Candidates(chain *Chain, count int) []*ChainBlock {
+
+	latest := make(map[string]*ChainBlock)
+	walkValid(chain.lastFinalisedBlock, func(chainBlock *ChainBlock) {
+		message, ok := latest[chainBlock.block.Validator]
+		if !ok || chainBlock.height > message.height {
+			latest[chainBlock.block.Validator] = chainBlock
+		}
+	})
+
+	// Votes count for the latest block and its ancestors up to the last finalised block
+	weights := make(map[*ChainBlock]int)
+	for _, message := range latest {
+		for chainBlock := message; chainBlock != chain.lastFinalisedBlock.parent; chainBlock = chainBlock.parent {
+			weights[chainBlock]++
+		}
+	}
+
+	return candidates(chain, ghostHeads(chain.lastFinalisedBlock, weights), count)
+}
+
+// Valid heads whose chain processes most incoming cross-shard transactions since the last
+// finalised block, the highest first on an equal number.
+type mostTXProcessed struct{}
+
+func (mostTXProcessed) Name() string {
+	return MostTXProcessed
+}
+
+func (mostTXProcessed) Candidates(chain *Chain, count int) []*ChainBlock {
+
+	processed := make(map[*ChainBlock]int)
+	heads := make([]*ChainBlock, 0)
+	walkValid(chain.lastFinalisedBlock, func(chainBlock *ChainBlock) {
+		if chainBlock != chain.lastFinalisedBlock {
+			processed[chainBlock] = processed[chainBlock.parent] + len(chainBlock.block.TXIn)
+		}
+		if isHead(chainBlock) {
+			heads = append(heads, chainBlock)
+		}
+	})
+
+	sort.SliceStable(heads, func(i, j int) bool {
+		if processed[heads[i]] == processed[heads[j]] {
+			return heads[i].height > heads[j].height
+		}
+		return processed[heads[i]] > processed[heads[j]]
+	})
+
+	return candidates(chain, heads, count)
+}
+
+// Valid heads in GHOST order: children are visited by descending weight, in order of arrival on equal weight.
+func ghostHeads(chainBlock *ChainBlock, weights map[*ChainBlock]int) []*ChainBlock {
+
+	if isHead(chainBlock) {
+		return []*ChainBlock{chainBlock}
+	}
+
+	children := make([]*ChainBlock, 0, len(chainBlock.children))
+	for _, child := range chainBlock.children {
+		if child.valid {
+			children = append(children, child)
+		}
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return weights[children[i]] > weights[children[j]]
+	})
+
+	heads := make([]*ChainBlock, 0)
+	for _, child := range children {
+		heads = append(heads, ghostHeads(child, weights)...)
+	}
+	return heads
+}
+
+// Valid block without valid children
+func isHead(chainBlock *ChainBlock) bool {
+	for _, child := range chainBlock.children {
+		if child.valid {
+			return false
+		}
+	}
+	return true
+}
+
+// Visit block and its valid descendants, parents before children
+func walkValid(chainBlock *ChainBlock, visit func(chainBlock *ChainBlock)) {
+	visit(chainBlock)
+	for _, child := range chainBlock.children {
+		if child.valid {
+			walkValid(child, visit)
+		}
+	}
+}
+
+// First count heads, followed by the ancestors of the best head like GetLongestChains if there are fewer heads
+func candidates(chain *Chain, heads []*ChainBlock, count int) []*ChainBlock {
+
+	result := make([]*ChainBlock, 0, count)
+	for i := 0; i < len(heads) && len(result) < count; i++ {
+		result = append(result, heads[i])
+	}
+
+	ancestor := heads[0]
+	for len(result) < count {
+		if ancestor != chain.lastFinalisedBlock {
+			ancestor = ancestor.parent
+		}
+		result = append(result, ancestor)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package chain
+
+import (
+	"testing"
+)
+
+func TestForkChoice(t *testing.T) {
+
+	tx1 := newTestTransaction(2, 1, "tx1")
+	tx2 := newTestTransaction(2, 1, "tx2")
+	tx3 := newTestTransaction(2, 1, "tx3")
+
+	// genesis - a1 - a2 - a3
+	//         \ b1 - b2
+	//              \ c2 (tx1, tx2)
+	//              \ d2
+	//              \ e2 (tx3) invalid
+	chain := newTestChain(1)
+	a1 := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	a2 := insertTestBlock(t, chain, a1, nil, nil)
+	a3 := insertTestBlock(t, chain, a2, nil, nil)
+	b1 := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	b2 := insertTestBlock(t, chain, b1, nil, nil)
+	c2 := insertTestBlock(t, chain, b1, []*Transaction{tx1, tx2}, nil)
+	d2 := insertTestBlock(t, chain, b1, nil, nil)
+	insertTestBlock(t, chain, b1, []*Transaction{tx3}, nil)
+	chain.UpdateConsistency([]*Transaction{tx1, tx2})
+
+	// Branch a is proposed by three validators, branch b by a single validator
+	for _, chainBlock := range []*ChainBlock{b1, b2, c2, d2} {
+		chainBlock.block.Validator = "b"
+	}
+
+	expected := map[string][]*ChainBlock{
+		LongestChain:    {a3, a2, b2},
+		HeaviestSubtree: {b2, c2, d2},
+		LatestMessage:   {a3, b2, c2},
+		MostTXProcessed: {c2, a3, b2},
+	}
+
+	for _, name := range ForkChoiceRules {
+		forkChoice, err := NewForkChoice(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if forkChoice.Name() != name {
+			t.Errorf("expected rule %s, got %s", name, forkChoice.Name())
+		}
+
+		candidates := forkChoice.Candidates(chain, 3)
+		for i := range candidates {
+			if candidates[i] != expected[name][i] {
+				t.Errorf("%s: candidate %d at height %d, expected %s at height %d", name, i,
+					candidates[i].height, expected[name][i].block.Validator, expected[name][i].height)
+			}
+		}
+	}
+
+	if _, err := NewForkChoice("ghost"); err == nil {
+		t.Errorf("expected error for unknown rule")
+	}
+}
+
+func TestForkChoiceSingleChain(t *testing.T) {
+
+	// Ancestors of the only head are candidates, down to the last finalised block
+	chain := newTestChain(1)
+	a := insertTestBlock(t, chain, chain.genesisBlock, nil, nil)
+	b := insertTestBlock(t, chain, a, nil, nil)
+	chain.Finalise(a.block.Hash)
+
+	forkChoice, _ := NewForkChoice(HeaviestSubtree)
+	candidates := forkChoice.Candidates(chain, 4)
+	expected := []*ChainBlock{b, a, a, a}
+	for i := range candidates {
+		if candidates[i] != expected[i] {
+			t.Errorf("candidate %d at height %d, expected height %d", i, candidates[i].height, expected[i].height)
+		}
+	}
+}
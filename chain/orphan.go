@@ -0,0 +1,115 @@
+package chain
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+// Time a block waits for its parent before it is discarded
+const DefaultOrphanTimeout = time.Minute
+
+// Counts of blocks that arrived before their parent
+type OrphanStats struct {
+	Received int `json:"received"`
+	Adopted  int `json:"adopted"`
+	Expired  int `json:"expired"`
+	Pending  int `json:"-"`
+}
+
+// Block waiting for its parent
+type orphan struct {
+	block    *Block
+	received time.Duration
+}
+
+// Blocks waiting for their parent, by parent hash
+type orphanPool struct {
+	timeout time.Duration
+	orphans map[string][]*orphan
+	stats   OrphanStats
+}
+
+func (pool *orphanPool) init(timeout time.Duration) {
+	pool.timeout = timeout
+	pool.orphans = make(map[string][]*orphan)
+	pool.stats = OrphanStats{}
+}
+
+// Add block waiting for its parent, unless it is already waiting
+func (pool *orphanPool) add(block *Block, now time.Duration) {
+
+	parentHash := string(block.ParentHash)
+	for _, orphan := range pool.orphans[parentHash] {
+		if bytes.Equal(orphan.block.Hash, block.Hash) {
+			return
+		}
+	}
+
+	pool.orphans[parentHash] = append(pool.orphans[parentHash], &orphan{block: block, received: now})
+	pool.stats.Received++
+}
+
+// Take blocks waiting for parent, in order of arrival
+func (pool *orphanPool) adopt(parentHash []byte) []*Block {
+
+	orphans := pool.orphans[string(parentHash)]
+	delete(pool.orphans, string(parentHash))
+
+	blocks := make([]*Block, len(orphans))
+	for i, orphan := range orphans {
+		blocks[i] = orphan.block
+	}
+	pool.stats.Adopted += len(blocks)
+	return blocks
+}
+
+// Discard blocks waiting longer than the timeout
+func (pool *orphanPool) expire(now time.Duration) {
+	for parentHash, orphans := range pool.orphans {
+
+		waiting := orphans[:0]
+		for _, orphan := range orphans {
+			if now-orphan.received < pool.timeout {
+				waiting = append(waiting, orphan)
+			}
+		}
+		pool.stats.Expired += len(orphans) - len(waiting)
+
+		if len(waiting) == 0 {
+			delete(pool.orphans, parentHash)
+		} else {
+			pool.orphans[parentHash] = waiting
+		}
+	}
+}
+
+// Waiting blocks ordered by arrival and hash
+func (pool *orphanPool) list() []*orphan {
+
+	list := make([]*orphan, 0)
+	for _, orphans := range pool.orphans {
+		list = append(list, orphans...)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].received == list[j].received {
+			return bytes.Compare(list[i].block.Hash, list[j].block.Hash) < 0
+		}
+		return list[i].received < list[j].received
+	})
+	return list
+}
+
+// Time a block with unknown parent waits in the orphan pool.
+func (chain *Chain) SetOrphanTimeout(timeout time.Duration) {
+	chain.orphans.timeout = timeout
+}
+
+// Counts of the orphan pool, including the blocks still waiting for their parent.
+func (chain *Chain) OrphanStats() OrphanStats {
+	stats := chain.orphans.stats
+	for _, orphans := range chain.orphans.orphans {
+		stats.Pending += len(orphans)
+	}
+	return stats
+}
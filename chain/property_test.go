@@ -0,0 +1,101 @@
+package chain
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Build random block tree with random TXIn drawn from the transaction pool.
+func randomTestTree(t *testing.T, random *rand.Rand, pool []*Transaction, size int) (*Chain, []*ChainBlock) {
+
+	chain := newTestChain(1)
+	blocks := []*ChainBlock{chain.genesisBlock}
+
+	for i := 0; i < size; i++ {
+		parent := blocks[random.Intn(len(blocks))]
+
+		txIn := make([]*Transaction, 0)
+		for _, j := range random.Perm(len(pool))[:random.Intn(3)] {
+			txIn = append(txIn, pool[j])
+		}
+
+		blocks = append(blocks, insertTestBlock(t, chain, parent, txIn, nil))
+	}
+
+	return chain, blocks
+}
+
+// Random subset of the transaction pool.
+func randomTxList(random *rand.Rand, pool []*Transaction) []*Transaction {
+	list := make([]*Transaction, 0)
+	for _, i := range random.Perm(len(pool))[:random.Intn(len(pool)+1)] {
+		list = append(list, pool[i])
+	}
+	return list
+}
+
+func TestPropertyConsistency(t *testing.T) {
+
+	for seed := int64(1); seed <= 100; seed++ {
+
+		random := rand.New(rand.NewSource(seed))
+
+		pool := make([]*Transaction, 8)
+		for i := range pool {
+			pool[i] = newTestTransaction(2, 1, fmt.Sprintf("tx %d", i))
+		}
+
+		chain, blocks := randomTestTree(t, random, pool, 30)
+
+		for round := 0; round < 10; round++ {
+
+			// Finalise a random valid descendant of the last finalised block
+			if candidate := blocks[random.Intn(len(blocks))]; candidate.valid && random.Intn(3) == 0 {
+				for ancestor := candidate; ancestor != nil; ancestor = ancestor.parent {
+					if ancestor == chain.lastFinalisedBlock {
+						chain.Finalise(candidate.block.Hash)
+						break
+					}
+				}
+			}
+
+			chain.UpdateConsistency(randomTxList(random, pool))
+
+			for _, block := range blocks {
+
+				// Finalised blocks never become invalid
+				if block.finalised && !block.valid {
+					t.Fatalf("seed %d: finalised block at height %d is invalid", seed, block.height)
+				}
+
+				// Children of invalid blocks are invalid
+				if block.parent != nil && !block.parent.valid && block.valid {
+					t.Fatalf("seed %d: valid block at height %d has invalid parent", seed, block.height)
+				}
+
+				// Valid blocks never process a transaction twice since the last finalised block
+				if block.valid && !block.finalised && isAncestor(chain.lastFinalisedBlock, block) {
+					txIn := chain.GetTXInList(block.block.Hash)
+					seen := make(map[string]bool)
+					for _, tx := range txIn {
+						if seen[tx.Hash] {
+							t.Fatalf("seed %d: valid block at height %d processes transaction twice", seed, block.height)
+						}
+						seen[tx.Hash] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// Whether block is ancestor of, or equal to, descendant
+func isAncestor(block *ChainBlock, descendant *ChainBlock) bool {
+	for ; descendant != nil; descendant = descendant.parent {
+		if descendant == block {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"sort"
+)
+
+// Verifier of the proposer of a block, see SetVerifier.
+type Verifier interface {
+	// Error unless the block is signed by the proposer of its slot
+	VerifyBlock(block *Block) error
+}
+
+// Sign the hash of the block, call after SetHash.
+func (block *Block) Sign(key ed25519.PrivateKey) {
+	block.Signature = ed25519.Sign(key, block.Hash)
+}
+
+// Error unless the hash matches the header and the signature is made with the key.
+func (block *Block) VerifySignature(key ed25519.PublicKey) error {
+	if !bytes.Equal(block.Hash, block.Header().Hash()) {
+		return fmt.Errorf("block %x: hash does not match header", block.Hash)
+	}
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, block.Hash, block.Signature) {
+		return fmt.Errorf("block %x: invalid signature of validator %s", block.Hash, block.Validator)
+	}
+	return nil
+}
+
+// Verify blocks before they are inserted, blocks failing verification or whose slot does not
+// follow the slot of their parent are rejected. Nil, the default, accepts all blocks.
+func (chain *Chain) SetVerifier(verifier Verifier) {
+	chain.verifier = verifier
+}
+
+// Number of blocks rejected by the verifier
+func (chain *Chain) Rejected() int {
+	return chain.rejected
+}
+
+// Blocks signed by one validator for the same slot. With verified blocks it proves the validator equivocated.
+type Equivocation struct {
+	Validator string
+	Slot      int
+	Blocks    []*Block
+}
+
+// Equivocations among the blocks in the tree, ordered by slot. Blocks removed by pruning are not included.
+func (chain *Chain) Equivocations() []Equivocation {
+
+	type proposal struct {
+		validator string
+		slot      int
+	}
+
+	proposals := make([]proposal, 0)
+	blocks := make(map[proposal][]*Block)
+	chain.walk(func(chainBlock *ChainBlock) {
+		if chainBlock == chain.genesisBlock {
+			return
+		}
+		key := proposal{validator: chainBlock.block.Validator, slot: chainBlock.block.Slot}
+		if _, ok := blocks[key]; !ok {
+			proposals = append(proposals, key)
+		}
+		blocks[key] = append(blocks[key], chainBlock.block)
+	})
+
+	equivocations := make([]Equivocation, 0)
+	for _, key := range proposals {
+		if len(blocks[key]) > 1 {
+			equivocations = append(equivocations, Equivocation{Validator: key.validator, Slot: key.slot, Blocks: blocks[key]})
+		}
+	}
+	sort.SliceStable(equivocations, func(i, j int) bool { return equivocations[i].Slot < equivocations[j].Slot })
+
+	return equivocations
+}
+
+// Vote of a beacon validator, its signature over the hash of the finalisation
+type Vote struct {
+	Validator string `json:"validator"`
+	Signature []byte `json:"signature"`
+}
+
+// Hash of the finalised blocks, inconsistent transactions and penalties, equal for every validator computing the
+// same finalisation. Votes are not included.
+func (finalisation *Finalisation) Hash() []byte {
+
+	content := struct {
+		Height         int
+		Blocks         [][]byte
+		InconsistentTX []string
+		Penalties      []int64
+	}{
+		Height:         finalisation.Height,
+		Blocks:         make([][]byte, len(finalisation.Blocks)),
+		InconsistentTX: make([]string, len(finalisation.InconsistentTX)),
+		Penalties:      finalisation.Penalties,
+	}
+	for i := range finalisation.Blocks {
+		content.Blocks[i] = finalisation.Blocks[i].Hash
+	}
+	for i, tx := range finalisation.InconsistentTX {
+		content.InconsistentTX[i] = tx.Hash
+	}
+	sort.Strings(content.InconsistentTX)
+
+	var encoded bytes.Buffer
+
+	encoder := gob.NewEncoder(&encoded)
+	encoder.Encode(content)
+
+	hash := sha256.Sum256(encoded.Bytes())
+	return hash[:]
+}
+
+// Add the vote of validator, signed with its key
+func (finalisation *Finalisation) Sign(validator string, key ed25519.PrivateKey) {
+	finalisation.Votes = append(finalisation.Votes, Vote{Validator: validator, Signature: ed25519.Sign(key, finalisation.Hash())})
+}
+
+// Error unless the vote is signed with the key
+func (finalisation *Finalisation) VerifyVote(vote Vote, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, finalisation.Hash(), vote.Signature) {
+		return fmt.Errorf("finalisation %d: invalid vote of validator %s", finalisation.Height, vote.Validator)
+	}
+	return nil
+}
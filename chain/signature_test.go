@@ -0,0 +1,189 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+// Verifier accepting blocks signed by the key of their validator
+type testVerifier map[string]ed25519.PublicKey
+
+func (verifier testVerifier) VerifyBlock(block *Block) error {
+	key, ok := verifier[block.Validator]
+	if !ok {
+		return fmt.Errorf("unknown validator %s", block.Validator)
+	}
+	return block.VerifySignature(key)
+}
+
+// Key pair of validator derived from its name
+func newTestKey(name string) ed25519.PrivateKey {
+	seed := sha256.Sum256([]byte(name))
+	return ed25519.NewKeyFromSeed(seed[:])
+}
+
+func newSignedBlock(parent *ChainBlock, slot int, validator string, txOut []*Transaction) *Block {
+	block := &Block{
+		Shard:      parent.block.Shard,
+		ParentHash: parent.block.Hash,
+		Slot:       slot,
+		TXIn:       []*Transaction{},
+		TXOut:      txOut,
+		Validator:  validator,
+	}
+	block.SetHash()
+	block.Sign(newTestKey(validator))
+	return block
+}
+
+func TestBlockSignature(t *testing.T) {
+
+	chain := newTestChain(1)
+	tx := newTestTransaction(1, 2, "tx")
+	block := newSignedBlock(chain.genesisBlock, 1, "alice", []*Transaction{tx})
+
+	alice := newTestKey("alice").Public().(ed25519.PublicKey)
+	bob := newTestKey("bob").Public().(ed25519.PublicKey)
+
+	if err := block.VerifySignature(alice); err != nil {
+		t.Errorf("expected valid signature: %v", err)
+	}
+	if err := block.VerifySignature(bob); err == nil {
+		t.Errorf("expected invalid signature for other key")
+	}
+
+	// Every header field is covered by the hash
+	for name, tamper := range map[string]func(block *Block){
+		"slot":      func(block *Block) { block.Slot++ },
+		"validator": func(block *Block) { block.Validator = "bob" },
+		"txIn":      func(block *Block) { block.TXIn = []*Transaction{tx} },
+		"txOut":     func(block *Block) { block.TXOut = nil },
+	} {
+		tampered := *block
+		tamper(&tampered)
+		if err := tampered.VerifySignature(alice); err == nil {
+			t.Errorf("expected error for tampered %s", name)
+		}
+	}
+
+	block.SetHash()
+	if block.Signature != nil {
+		t.Errorf("expected signature cleared by SetHash")
+	}
+}
+
+func TestInsertVerified(t *testing.T) {
+
+	chain := newTestChain(1)
+	chain.SetVerifier(testVerifier{
+		"alice": newTestKey("alice").Public().(ed25519.PublicKey),
+		"bob":   newTestKey("bob").Public().(ed25519.PublicKey),
+	})
+
+	a := newSignedBlock(chain.genesisBlock, 1, "alice", nil)
+	chain.Insert(a)
+	if chain.Search(a.Hash) == nil {
+		t.Fatalf("signed block not inserted")
+	}
+	chainBlock := chain.Search(a.Hash)
+
+	// Signature of an unknown validator, unsigned block, signature of another validator, slot of the parent
+	forged := newSignedBlock(chainBlock, 2, "mallory", nil)
+	unsigned := newSignedBlock(chainBlock, 2, "bob", []*Transaction{newTestTransaction(1, 2, "unsigned")})
+	unsigned.Signature = nil
+	impersonated := newSignedBlock(chainBlock, 2, "alice", []*Transaction{newTestTransaction(1, 2, "impersonated")})
+	impersonated.Validator = "bob"
+	wrongSlot := newSignedBlock(chainBlock, 1, "bob", nil)
+
+	for _, block := range []*Block{forged, unsigned, impersonated, wrongSlot} {
+		chain.Insert(block)
+		if chain.Search(block.Hash) != nil {
+			t.Errorf("block of %s in slot %d inserted", block.Validator, block.Slot)
+		}
+	}
+
+	// Slots can be skipped, an orphan in an earlier slot is rejected when its parent arrives
+	b := newSignedBlock(chainBlock, 4, "bob", nil)
+	orphan := &Block{Shard: 1, ParentHash: b.Hash, Slot: 3, Validator: "alice"}
+	orphan.SetHash()
+	orphan.Sign(newTestKey("alice"))
+	chain.Insert(orphan)
+	chain.Insert(b)
+	if chain.Search(b.Hash) == nil || chain.Search(orphan.Hash) != nil {
+		t.Errorf("expected block in slot 4 inserted and orphan in slot 3 rejected")
+	}
+
+	if chain.Rejected() != 5 {
+		t.Errorf("expected 5 rejected blocks, got %d", chain.Rejected())
+	}
+	if chain.Copy().Rejected() != 5 {
+		t.Errorf("expected rejected blocks counted in copy")
+	}
+}
+
+func TestEquivocations(t *testing.T) {
+
+	chain := newTestChain(1)
+	genesis := chain.genesisBlock
+
+	a := newSignedBlock(genesis, 1, "alice", nil)
+	sibling := newSignedBlock(genesis, 1, "alice", []*Transaction{newTestTransaction(1, 2, "sibling")})
+	b := newSignedBlock(genesis, 2, "bob", nil)
+	chain.Insert(a)
+	chain.Insert(sibling)
+	chain.Insert(b)
+
+	// Blocks of one validator in different slots are no equivocation
+	c := newSignedBlock(chain.Search(b.Hash), 3, "alice", nil)
+	chain.Insert(c)
+
+	equivocations := chain.Equivocations()
+	if len(equivocations) != 1 {
+		t.Fatalf("expected 1 equivocation, got %d", len(equivocations))
+	}
+	if equivocation := equivocations[0]; equivocation.Validator != "alice" || equivocation.Slot != 1 || len(equivocation.Blocks) != 2 {
+		t.Errorf("unexpected equivocation of %s in slot %d with %d blocks", equivocation.Validator, equivocation.Slot, len(equivocation.Blocks))
+	}
+}
+
+func TestFinalisationVotes(t *testing.T) {
+
+	chain := newTestChain(1)
+	block := newSignedBlock(chain.genesisBlock, 1, "alice", nil)
+	first, second := newTestTransaction(1, 2, "first"), newTestTransaction(1, 2, "second")
+
+	finalisation := &Finalisation{Height: 1, Blocks: []Block{*block}, InconsistentTX: []*Transaction{first, second}, Penalties: []int64{0, 0, 20}}
+	reordered := &Finalisation{Height: 1, Blocks: []Block{*block}, InconsistentTX: []*Transaction{second, first}, Penalties: []int64{0, 0, 20}}
+
+	// Validators computing the same finalisation vote for the same hash
+	finalisation.Sign("alice", newTestKey("alice"))
+	if !bytes.Equal(finalisation.Hash(), reordered.Hash()) {
+		t.Errorf("expected hash independent of TX order and votes")
+	}
+
+	alice := newTestKey("alice").Public().(ed25519.PublicKey)
+	bob := newTestKey("bob").Public().(ed25519.PublicKey)
+
+	if err := reordered.VerifyVote(finalisation.Votes[0], alice); err != nil {
+		t.Errorf("expected valid vote: %v", err)
+	}
+	if err := finalisation.VerifyVote(finalisation.Votes[0], bob); err == nil {
+		t.Errorf("expected invalid vote for other key")
+	}
+
+	for name, tamper := range map[string]func(finalisation *Finalisation){
+		"height":         func(finalisation *Finalisation) { finalisation.Height++ },
+		"blocks":         func(finalisation *Finalisation) { finalisation.Blocks = nil },
+		"inconsistentTX": func(finalisation *Finalisation) { finalisation.InconsistentTX = finalisation.InconsistentTX[1:] },
+		"penalties":      func(finalisation *Finalisation) { finalisation.Penalties = []int64{0, 0, 10} },
+	} {
+		tampered := *finalisation
+		tamper(&tampered)
+		if err := tampered.VerifyVote(finalisation.Votes[0], alice); err == nil {
+			t.Errorf("expected error for tampered %s", name)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package chain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Transactions of a snapshot by hex encoded hash, shared by all block trees and transaction pools.
+type TransactionTable map[string]*Transaction
+
+// Table of transactions, e.g. read from a snapshot.
+func NewTransactionTable(transactions []*Transaction) TransactionTable {
+	table := make(TransactionTable, len(transactions))
+	for _, tx := range transactions {
+		table.Add(tx)
+	}
+	return table
+}
+
+// Add transaction and return its reference.
+func (table TransactionTable) Add(tx *Transaction) string {
+	ref := hex.EncodeToString([]byte(tx.Hash))
+	if _, ok := table[ref]; !ok {
+		table[ref] = tx
+	}
+	return ref
+}
+
+// Add transactions and return their references.
+func (table TransactionTable) Refs(transactions []*Transaction) []string {
+	refs := make([]string, len(transactions))
+	for i, tx := range transactions {
+		refs[i] = table.Add(tx)
+	}
+	return refs
+}
+
+// Transactions of the references.
+func (table TransactionTable) Resolve(refs []string) ([]*Transaction, error) {
+	transactions := make([]*Transaction, len(refs))
+	for i, ref := range refs {
+		tx, ok := table[ref]
+		if !ok {
+			return nil, fmt.Errorf("unknown transaction %s", ref)
+		}
+		transactions[i] = tx
+	}
+	return transactions, nil
+}
+
+// All transactions ordered by hash.
+func (table TransactionTable) List() []*Transaction {
+
+	refs := make([]string, 0, len(table))
+	for ref := range table {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	transactions := make([]*Transaction, len(refs))
+	for i, ref := range refs {
+		transactions[i] = table[ref]
+	}
+	return transactions
+}
+
+// Serialisable block, transactions are referenced by hash.
+type BlockSnapshot struct {
+	Shard      int      `json:"shard"`
+	Hash       []byte   `json:"hash"`
+	ParentHash []byte   `json:"parentHash,omitempty"`
+	Slot       int      `json:"slot,omitempty"`
+	TXIn       []string `json:"txIn"`
+	TXOut      []string `json:"txOut"`
+	Validator  string   `json:"validator,omitempty"`
+	Signature  []byte   `json:"signature,omitempty"`
+}
+
+// Serialisable block of a block tree
+type ChainBlockSnapshot struct {
+	BlockSnapshot
+	Valid      bool       `json:"valid"`
+	Finalised  bool       `json:"finalised"`
+	Coordinate Coordinate `json:"coordinate"`
+}
+
+// Serialisable block waiting for its parent
+type OrphanSnapshot struct {
+	BlockSnapshot
+	Received time.Duration `json:"received"`
+}
+
+// Serialisable block tree, blocks are ordered parents first. The root is the genesis block, unless pruned.
+type ChainSnapshot struct {
+	Blocks        []ChainBlockSnapshot `json:"blocks"`
+	RootHeight    int                  `json:"rootHeight,omitempty"`
+	Pruned        int                  `json:"pruned,omitempty"`
+	Rejected      int                  `json:"rejected,omitempty"`
+	LastFinalised []byte               `json:"lastFinalised"`
+	Orphans       []OrphanSnapshot     `json:"orphans,omitempty"`
+	OrphanStats   OrphanStats          `json:"orphanStats"`
+}
+
+// Serialisable finalisation
+type FinalisationSnapshot struct {
+	Height         int             `json:"height"`
+	Blocks         []BlockSnapshot `json:"blocks"`
+	InconsistentTX []string        `json:"inconsistentTX"`
+	Penalties      []int64         `json:"penalties,omitempty"`
+	Votes          []Vote          `json:"votes,omitempty"`
+}
+
+func NewBlockSnapshot(block *Block, table TransactionTable) BlockSnapshot {
+	return BlockSnapshot{
+		Shard:      block.Shard,
+		Hash:       block.Hash,
+		ParentHash: block.ParentHash,
+		Slot:       block.Slot,
+		TXIn:       table.Refs(block.TXIn),
+		TXOut:      table.Refs(block.TXOut),
+		Validator:  block.Validator,
+		Signature:  block.Signature,
+	}
+}
+
+// Block of the snapshot with the transactions from the table.
+func (snapshot *BlockSnapshot) Restore(table TransactionTable) (*Block, error) {
+
+	txIn, err := table.Resolve(snapshot.TXIn)
+	if err != nil {
+		return nil, err
+	}
+	txOut, err := table.Resolve(snapshot.TXOut)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Block{
+		Shard:      snapshot.Shard,
+		Hash:       snapshot.Hash,
+		ParentHash: snapshot.ParentHash,
+		Slot:       snapshot.Slot,
+		TXIn:       txIn,
+		TXOut:      txOut,
+		Validator:  snapshot.Validator,
+		Signature:  snapshot.Signature,
+	}, nil
+}
+
+// Snapshot of the block tree, transactions are added to the table.
+func (chain *Chain) Snapshot(table TransactionTable) ChainSnapshot {
+
+	snapshot := ChainSnapshot{
+		Blocks:        make([]ChainBlockSnapshot, 0),
+		RootHeight:    chain.genesisBlock.height,
+		Pruned:        chain.pruned,
+		Rejected:      chain.rejected,
+		LastFinalised: chain.lastFinalisedBlock.block.Hash,
+		OrphanStats:   chain.orphans.stats,
+	}
+
+	chain.walk(func(chainBlock *ChainBlock) {
+		snapshot.Blocks = append(snapshot.Blocks, ChainBlockSnapshot{
+			BlockSnapshot: NewBlockSnapshot(chainBlock.block, table),
+			Valid:         chainBlock.valid,
+			Finalised:     chainBlock.finalised,
+			Coordinate:    chainBlock.coordinate,
+		})
+	})
+
+	for _, orphan := range chain.orphans.list() {
+		snapshot.Orphans = append(snapshot.Orphans, OrphanSnapshot{
+			BlockSnapshot: NewBlockSnapshot(orphan.block, table),
+			Received:      orphan.received,
+		})
+	}
+
+	return snapshot
+}
+
+// Replace the block tree by the snapshot, the chain must be initialised.
+func (chain *Chain) Restore(snapshot ChainSnapshot, table TransactionTable) error {
+
+	if len(snapshot.Blocks) == 0 {
+		return fmt.Errorf("block tree without genesis block")
+	}
+
+	chainBlocks := make(map[string]*ChainBlock, len(snapshot.Blocks))
+	var genesisBlock *ChainBlock
+
+	for i := range snapshot.Blocks {
+		blockSnapshot := &snapshot.Blocks[i]
+
+		block, err := blockSnapshot.Restore(table)
+		if err != nil {
+			return fmt.Errorf("block %x: %v", blockSnapshot.Hash, err)
+		}
+
+		chainBlock := &ChainBlock{
+			block:      block,
+			children:   []*ChainBlock{},
+			valid:      blockSnapshot.Valid,
+			finalised:  blockSnapshot.Finalised,
+			coordinate: blockSnapshot.Coordinate,
+		}
+
+		if i == 0 {
+			chainBlock.height = snapshot.RootHeight
+			genesisBlock = chainBlock
+		} else {
+			parent, ok := chainBlocks[string(block.ParentHash)]
+			if !ok {
+				return fmt.Errorf("block %x: unknown parent %x", block.Hash, block.ParentHash)
+			}
+			chainBlock.parent = parent
+			chainBlock.height = parent.height + 1
+			parent.children = append(parent.children, chainBlock)
+		}
+
+		chainBlocks[string(block.Hash)] = chainBlock
+	}
+
+	lastFinalisedBlock, ok := chainBlocks[string(snapshot.LastFinalised)]
+	if !ok {
+		return fmt.Errorf("unknown last finalised block %x", snapshot.LastFinalised)
+	}
+
+	// Orphan pool keeps its timeout
+	chain.orphans.init(chain.orphans.timeout)
+	for i := range snapshot.Orphans {
+		block, err := snapshot.Orphans[i].Restore(table)
+		if err != nil {
+			return fmt.Errorf("orphan %x: %v", snapshot.Orphans[i].Hash, err)
+		}
+		chain.orphans.add(block, snapshot.Orphans[i].Received)
+	}
+	chain.orphans.stats = snapshot.OrphanStats
+
+	chain.genesisBlock = genesisBlock
+	chain.lastFinalisedBlock = lastFinalisedBlock
+	chain.blocks = chainBlocks
+	chain.pruned = snapshot.Pruned
+	chain.rejected = snapshot.Rejected
+	chain.changed()
+	return nil
+}
+
+// Snapshot of the finalisation, transactions are added to the table.
+func (finalisation *Finalisation) Snapshot(table TransactionTable) FinalisationSnapshot {
+
+	snapshot := FinalisationSnapshot{
+		Height:         finalisation.Height,
+		Blocks:         make([]BlockSnapshot, len(finalisation.Blocks)),
+		InconsistentTX: table.Refs(finalisation.InconsistentTX),
+		Penalties:      finalisation.Penalties,
+		Votes:          finalisation.Votes,
+	}
+	for i := range finalisation.Blocks {
+		snapshot.Blocks[i] = NewBlockSnapshot(&finalisation.Blocks[i], table)
+	}
+
+	return snapshot
+}
+
+// Finalisation of the snapshot with the transactions from the table.
+func (snapshot *FinalisationSnapshot) Restore(table TransactionTable) (*Finalisation, error) {
+
+	inconsistentTX, err := table.Resolve(snapshot.InconsistentTX)
+	if err != nil {
+		return nil, err
+	}
+
+	finalisation := &Finalisation{
+		Height:         snapshot.Height,
+		Blocks:         make([]Block, len(snapshot.Blocks)),
+		InconsistentTX: inconsistentTX,
+		Penalties:      snapshot.Penalties,
+		Votes:          snapshot.Votes,
+	}
+	for i := range snapshot.Blocks {
+		block, err := snapshot.Blocks[i].Restore(table)
+		if err != nil {
+			return nil, err
+		}
+		finalisation.Blocks[i] = *block
+	}
+
+	return finalisation, nil
+}
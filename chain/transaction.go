@@ -0,0 +1,100 @@
+package chain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Cross-shard transaction, shared by the blocks and pools of all actors. It never changes once created,
+// the times it reaches later stages are kept by the simulation metrics.
+type Transaction struct {
+	SourceShard int    `json:"sourceShard"`
+	TargetShard int    `json:"targetShard"`
+	Hash        string `json:"hash"`
+	Data        string `json:"data"`
+	// Simulation time of creation
+	Created time.Duration `json:"created"`
+}
+
+// Calculate Hash of Block
+func (tx *Transaction) SetHash() {
+
+	tx.Hash = ""
+
+	var encoded bytes.Buffer
+	var hash [32]byte
+
+	encoder := gob.NewEncoder(&encoded)
+	encoder.Encode(tx)
+
+	hash = sha256.Sum256(encoded.Bytes())
+	tx.Hash = string(hash[:])
+
+}
+
+// Encode transaction as JSON with hex encoded hash, the raw hash is not valid UTF-8.
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	type transaction Transaction
+	return json.Marshal(struct {
+		transaction
+		Hash string `json:"hash"`
+	}{transaction(tx), hex.EncodeToString([]byte(tx.Hash))})
+}
+
+// Decode transaction from JSON with hex encoded hash.
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	type transaction Transaction
+	decoded := struct {
+		*transaction
+		Hash string `json:"hash"`
+	}{transaction: (*transaction)(tx)}
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	hash, err := hex.DecodeString(decoded.Hash)
+	if err != nil {
+		return fmt.Errorf("transaction hash: %v", err)
+	}
+	tx.Hash = string(hash)
+	return nil
+}
+
+func (tx *Transaction) prettyPrint() {
+	fmt.Printf("SourceShard:  %d - TargetShard: %d  -  Data: %s  \n", tx.SourceShard,  tx.TargetShard, tx.Data)
+}
+
+
+// Remove item from list and return
+func ContainsTx(tx *Transaction, txListPointer *[]*Transaction) bool {
+
+	for _, transaction := range *txListPointer {
+		if tx.Hash == transaction.Hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove item from list and return
+func RemoveTxFromList(deleteTX *Transaction, txListPointer *[]*Transaction) bool {
+
+	txList := *txListPointer
+
+	for i, transaction := range txList {
+		if deleteTX.Hash == transaction.Hash {
+			txList[i] = txList[len(txList)-1]     // Copy last element to index i
+			txList = txList[:len(txList)-1] // Truncate slice
+			*txListPointer = txList
+			return true
+		}
+	}
+
+	return false
+}
\ No newline at end of file
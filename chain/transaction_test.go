@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSetHash(t *testing.T) {
+
+	tx1 := newTestTransaction(1, 2, "data")
+	tx2 := newTestTransaction(1, 2, "data")
+	tx3 := newTestTransaction(1, 2, "other")
+
+	if tx1.Hash != tx2.Hash {
+		t.Errorf("equal transactions must have equal hashes")
+	}
+	if tx1.Hash == tx3.Hash {
+		t.Errorf("different transactions must have different hashes")
+	}
+}
+
+func TestRemoveTxFromList(t *testing.T) {
+
+	a := newTestTransaction(1, 2, "a")
+	b := newTestTransaction(1, 2, "b")
+	c := newTestTransaction(1, 2, "c")
+
+	list := []*Transaction{a, b, a}
+
+	if !ContainsTx(a, &list) || ContainsTx(c, &list) {
+		t.Errorf("unexpected ContainsTx result")
+	}
+
+	// Removes one occurrence only
+	if !RemoveTxFromList(a, &list) {
+		t.Errorf("expected a to be removed")
+	}
+	if len(list) != 2 || !ContainsTx(a, &list) {
+		t.Errorf("expected one a to remain, got list of %d", len(list))
+	}
+
+	if RemoveTxFromList(c, &list) {
+		t.Errorf("c is not in list")
+	}
+	if !RemoveTxFromList(a, &list) || !RemoveTxFromList(b, &list) || len(list) != 0 {
+		t.Errorf("expected empty list, got %d", len(list))
+	}
+}
+
+func TestTransactionJSON(t *testing.T) {
+
+	tx := newTestTransaction(1, 2, "data")
+	tx.Created = 3
+
+	encoded, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Transaction
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != *tx {
+		t.Errorf("expected %+v, got %+v", *tx, decoded)
+	}
+}
@@ -0,0 +1,115 @@
+package chain
+
+// Multiset of transactions keyed by hash, in the order of a transaction list.
+// Remove moves the last transaction into the gap, like RemoveTxFromList, so
+// random picks from the list stay the same as with a plain slice.
+type TxSet struct {
+	list  []*Transaction
+	index map[string]txPositions
+}
+
+// Positions of a transaction in the list, duplicates are rare so only they need an allocation
+type txPositions struct {
+	first      int
+	duplicates []int
+}
+
+func NewTxSet(transactions []*Transaction) *TxSet {
+	set := &TxSet{
+		list:  make([]*Transaction, 0, len(transactions)),
+		index: make(map[string]txPositions, len(transactions)),
+	}
+	for _, tx := range transactions {
+		set.Add(tx)
+	}
+	return set
+}
+
+// Add transaction at the end of the list, a transaction can be added more than once.
+func (set *TxSet) Add(tx *Transaction) {
+	if positions, ok := set.index[tx.Hash]; ok {
+		positions.duplicates = append(positions.duplicates, len(set.list))
+		set.index[tx.Hash] = positions
+	} else {
+		set.index[tx.Hash] = txPositions{first: len(set.list)}
+	}
+	set.list = append(set.list, tx)
+}
+
+func (set *TxSet) Contains(tx *Transaction) bool {
+	_, ok := set.index[tx.Hash]
+	return ok
+}
+
+// Number of times the transaction is in the set
+func (set *TxSet) Count(tx *Transaction) int {
+	positions, ok := set.index[tx.Hash]
+	if !ok {
+		return 0
+	}
+	return 1 + len(positions.duplicates)
+}
+
+// Remove first occurrence of the transaction, false if it is not in the set.
+func (set *TxSet) Remove(tx *Transaction) bool {
+
+	positions, ok := set.index[tx.Hash]
+	if !ok {
+		return false
+	}
+
+	// Take lowest position, like the first match of RemoveTxFromList
+	position := positions.first
+	if len(positions.duplicates) == 0 {
+		delete(set.index, tx.Hash)
+	} else {
+		lowest := 0
+		for i := range positions.duplicates {
+			if positions.duplicates[i] < positions.duplicates[lowest] {
+				lowest = i
+			}
+		}
+		if positions.duplicates[lowest] < position {
+			position, positions.duplicates[lowest] = positions.duplicates[lowest], position
+		}
+		positions.first = positions.duplicates[lowest]
+		positions.duplicates[lowest] = positions.duplicates[len(positions.duplicates)-1]
+		positions.duplicates = positions.duplicates[:len(positions.duplicates)-1]
+		set.index[tx.Hash] = positions
+	}
+
+	// Move last transaction into the gap
+	last := len(set.list) - 1
+	if position != last {
+		moved := set.list[last]
+		set.list[position] = moved
+		set.index[moved.Hash] = set.index[moved.Hash].move(last, position)
+	}
+	set.list[last] = nil
+	set.list = set.list[:last]
+
+	return true
+}
+
+func (set *TxSet) Len() int {
+	return len(set.list)
+}
+
+// Transactions in list order, the list must not be modified.
+func (set *TxSet) List() []*Transaction {
+	return set.list
+}
+
+func (positions txPositions) move(from int, to int) txPositions {
+	if positions.first == from {
+		positions.first = to
+		return positions
+	}
+	for i := range positions.duplicates {
+		if positions.duplicates[i] == from {
+			positions.duplicates[i] = to
+			break
+		}
+	}
+	return positions
+}
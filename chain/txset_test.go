@@ -0,0 +1,58 @@
+package chain
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestTxSet(t *testing.T) {
+
+	a := newTestTransaction(1, 2, "a")
+	b := newTestTransaction(1, 2, "b")
+	c := newTestTransaction(1, 2, "c")
+
+	set := NewTxSet([]*Transaction{a, b, a})
+
+	if !set.Contains(a) || set.Contains(c) || set.Count(a) != 2 || set.Len() != 3 {
+		t.Errorf("unexpected set of %d transactions", set.Len())
+	}
+
+	// Removes one occurrence only
+	if !set.Remove(a) || !set.Contains(a) || set.Len() != 2 {
+		t.Errorf("expected one a to remain")
+	}
+	if set.Remove(c) {
+		t.Errorf("c is not in set")
+	}
+	if !set.Remove(a) || !set.Remove(b) || set.Len() != 0 || set.Contains(a) {
+		t.Errorf("expected empty set, got %d", set.Len())
+	}
+}
+
+// Set keeps the same order as a list updated with RemoveTxFromList.
+func TestTxSetOrder(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+	pool := make([]*Transaction, 20)
+	for i := range pool {
+		pool[i] = newTestTransaction(1, 2, string(rune('a'+i)))
+	}
+
+	list := make([]*Transaction, 0)
+	set := NewTxSet(nil)
+
+	for i := 0; i < 1000; i++ {
+		tx := pool[random.Intn(len(pool))]
+		if random.Intn(2) == 0 {
+			list = append(list, tx)
+			set.Add(tx)
+		} else if RemoveTxFromList(tx, &list) != set.Remove(tx) {
+			t.Fatalf("step %d: remove differs", i)
+		}
+
+		if !reflect.DeepEqual(list, set.List()) {
+			t.Fatalf("step %d: set order differs from list", i)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package clock provides the real-time and discrete-event clocks driving a simulation.
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Clock provides the simulation time to the beacon, shards and chains.
+type Clock interface {
+
+	// Elapsed simulation time since start
+	Now() time.Duration
+
+	// Channel receiving once the time period has elapsed
+	After(period time.Duration) <-chan time.Time
+
+	// Call f once the time period has elapsed, e.g. to deliver a delayed message
+	AfterFunc(period time.Duration, f func())
+
+	// Let the simulation run for the time period
+	Advance(period time.Duration)
+
+	// Begin and Done bracket the handling of a message or timer.
+	// A discrete-event clock only moves forward when all work is done.
+	Begin()
+	Done()
+}
+
+// RealClock follows the wall clock, used by the visualiser.
+type RealClock struct {
+	start time.Time
+}
+
+func NewRealClock() *RealClock {
+	return NewRealClockAt(0)
+}
+
+// Real clock continuing at elapsed time now, e.g. when restoring a snapshot.
+func NewRealClockAt(now time.Duration) *RealClock {
+	return &RealClock{start: time.Now().Add(-now)}
+}
+
+func (clock *RealClock) Now() time.Duration {
+	return time.Now().Sub(clock.start)
+}
+
+func (clock *RealClock) After(period time.Duration) <-chan time.Time {
+	return time.After(period)
+}
+
+func (clock *RealClock) AfterFunc(period time.Duration, f func()) {
+	time.AfterFunc(period, f)
+}
+
+func (clock *RealClock) Advance(period time.Duration) {
+	time.Sleep(period)
+}
+
+func (clock *RealClock) Begin() {}
+
+func (clock *RealClock) Done() {}
+
+// ManualClock is only moved by Advance, used to replay a trace. Timers never fire.
+type ManualClock struct {
+	now time.Duration
+}
+
+func NewManualClock() *ManualClock {
+	return &ManualClock{}
+}
+
+func (clock *ManualClock) Now() time.Duration {
+	return clock.now
+}
+
+func (clock *ManualClock) After(period time.Duration) <-chan time.Time {
+	return nil
+}
+
+func (clock *ManualClock) AfterFunc(period time.Duration, f func()) {}
+
+func (clock *ManualClock) Advance(period time.Duration) {
+	clock.now += period
+}
+
+func (clock *ManualClock) Begin() {}
+
+func (clock *ManualClock) Done() {}
+
+// EventClock is a discrete-event scheduler running the simulation in virtual time.
+// Timers fire one by one in time order, the next timer only fires when the beacon
+// and shards have handled the previous one and all messages it caused.
+type EventClock struct {
+	mutex    sync.Mutex
+	now      time.Duration
+	sequence uint64
+	timers   timerQueue
+	pending  sync.WaitGroup
+}
+
+type timer struct {
+	at       time.Duration
+	sequence uint64
+	channel  chan time.Time
+	f        func()
+}
+
+func NewEventClock() *EventClock {
+	return NewEventClockAt(0)
+}
+
+// Event clock starting at virtual time now, e.g. when restoring a snapshot.
+func NewEventClockAt(now time.Duration) *EventClock {
+	return &EventClock{now: now}
+}
+
+func (clock *EventClock) Now() time.Duration {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *EventClock) After(period time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	// Sequence number breaks ties between timers firing at the same time
+	clock.sequence++
+	t := &timer{
+		at:       clock.now + period,
+		sequence: clock.sequence,
+		channel:  make(chan time.Time, 1),
+	}
+	heap.Push(&clock.timers, t)
+
+	return t.channel
+}
+
+func (clock *EventClock) AfterFunc(period time.Duration, f func()) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.sequence++
+	heap.Push(&clock.timers, &timer{
+		at:       clock.now + period,
+		sequence: clock.sequence,
+		f:        f,
+	})
+}
+
+// Fire timers in time order until the time period has elapsed.
+func (clock *EventClock) Advance(period time.Duration) {
+
+	until := clock.Now() + period
+
+	for {
+		// Wait until all actors are idle
+		clock.pending.Wait()
+
+		clock.mutex.Lock()
+		if len(clock.timers) == 0 || clock.timers[0].at > until {
+			clock.now = until
+			clock.mutex.Unlock()
+			return
+		}
+		t := heap.Pop(&clock.timers).(*timer)
+		clock.now = t.at
+		clock.mutex.Unlock()
+
+		if t.f != nil {
+			t.f()
+			continue
+		}
+
+		clock.Begin()
+		t.channel <- time.Unix(0, 0).Add(t.at)
+	}
+}
+
+func (clock *EventClock) Begin() {
+	clock.pending.Add(1)
+}
+
+func (clock *EventClock) Done() {
+	clock.pending.Done()
+}
+
+// Priority queue of timers, earliest timer first
+type timerQueue []*timer
+
+func (queue timerQueue) Len() int {
+	return len(queue)
+}
+
+func (queue timerQueue) Less(i, j int) bool {
+	if queue[i].at == queue[j].at {
+		return queue[i].sequence < queue[j].sequence
+	}
+	return queue[i].at < queue[j].at
+}
+
+func (queue timerQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+}
+
+func (queue *timerQueue) Push(x interface{}) {
+	*queue = append(*queue, x.(*timer))
+}
+
+func (queue *timerQueue) Pop() interface{} {
+	old := *queue
+	t := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,107 @@
+package clock
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventClockOrder(t *testing.T) {
+
+	clock := NewEventClockAt(10 * time.Second)
+	fired := make([]time.Duration, 0)
+	for _, period := range []time.Duration{3, 1, 5, 2} {
+		clock.AfterFunc(period*time.Second, func() { fired = append(fired, clock.Now()) })
+	}
+
+	clock.Advance(4 * time.Second)
+
+	expected := []time.Duration{11 * time.Second, 12 * time.Second, 13 * time.Second}
+	if !reflect.DeepEqual(fired, expected) {
+		t.Errorf("expected timers fired at %v, got %v", expected, fired)
+	}
+	if now := clock.Now(); now != 14*time.Second {
+		t.Errorf("expected time 14s, got %s", now)
+	}
+
+	// The remaining timer fires in the next period
+	clock.Advance(time.Second)
+	if len(fired) != 4 || fired[3] != 15*time.Second {
+		t.Errorf("expected last timer fired at 15s, got %v", fired)
+	}
+}
+
+// Timers firing at the same time fire in the order they were set
+func TestEventClockTies(t *testing.T) {
+
+	clock := NewEventClock()
+	fired := make([]string, 0)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		name := name
+		clock.AfterFunc(time.Second, func() { fired = append(fired, name) })
+	}
+
+	clock.Advance(time.Second)
+
+	if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(fired, expected) {
+		t.Errorf("expected %v, got %v", expected, fired)
+	}
+}
+
+// Timers set by a firing timer fire in the same period
+func TestEventClockAfterFunc(t *testing.T) {
+
+	clock := NewEventClock()
+	fired := make([]time.Duration, 0)
+	clock.AfterFunc(time.Second, func() {
+		fired = append(fired, clock.Now())
+		clock.AfterFunc(0, func() { fired = append(fired, clock.Now()) })
+		clock.AfterFunc(2*time.Second, func() { fired = append(fired, clock.Now()) })
+		clock.AfterFunc(5*time.Second, func() { fired = append(fired, clock.Now()) })
+	})
+
+	clock.Advance(4 * time.Second)
+
+	expected := []time.Duration{time.Second, time.Second, 3 * time.Second}
+	if !reflect.DeepEqual(fired, expected) {
+		t.Errorf("expected timers fired at %v, got %v", expected, fired)
+	}
+}
+
+// A timer only fires when the actors have handled the previous timer and the work it caused
+func TestEventClockAdvanceWaits(t *testing.T) {
+
+	clock := NewEventClock()
+
+	var mutex sync.Mutex
+	events := make([]string, 0)
+	record := func(event string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	}
+
+	// Actor handling a timer passes a message to another actor, which takes a while
+	timer := clock.After(time.Second)
+	clock.AfterFunc(time.Second, func() { record("next timer") })
+	go func() {
+		<-timer
+		clock.Begin()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			record("message")
+			clock.Done()
+		}()
+		record("timer")
+		clock.Done()
+	}()
+
+	clock.Advance(2 * time.Second)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if expected := []string{"timer", "message", "next timer"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/simulation"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+func main() {
+
+	headless := flag.Bool("headless", false, "run the simulation without visualiser")
+	duration := flag.Duration("duration", time.Minute, "duration of a headless simulation run")
+	virtual := flag.Bool("virtual", false, "run a headless simulation on a discrete-event clock in virtual time")
+	stakePath := flag.String("stake-csv", "", "write stake history of every shard committee as CSV after a headless run")
+	configPath := flag.String("config", "", "scenario file (JSON) with simulation parameters")
+	dotPath := flag.String("dot", "", "write block DAG of all shards as Graphviz DOT after a headless run")
+	dagPath := flag.String("dag-json", "", "write block DAG of all shards as JSON after a headless run")
+	tracePath := flag.String("trace", "", "record events of the beacon and shards as JSON Lines")
+	replayPath := flag.String("replay", "", "replay a recorded trace in the visualiser instead of running a simulation")
+	snapshotPath := flag.String("snapshot", "", "write snapshot of the simulation state after a headless run")
+	restorePath := flag.String("restore", "", "continue a simulation from a snapshot, with the parameters of the snapshot")
+
+	config := simulation.DefaultConfig()
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Command line flags override the scenario file
+	if *configPath != "" {
+		if err := config.LoadScenario(*configPath, flag.CommandLine, os.Args[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	// Replay recorded trace
+	if *replayPath != "" {
+		if *headless {
+			fmt.Println("A trace can only be replayed in the visualiser.")
+			os.Exit(2)
+		}
+
+		events, err := readTrace(*replayPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		replay := simulation.Replay{}
+		if err := replay.Init(&config, events); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+
+		fmt.Println("Replaying trace:", replay.Len(), "events of", config.ShardCount, "shards.")
+
+		visualiser := Visualiser{
+			replay:    &replay,
+			config:    &config,
+			viewShard: 0,
+			scaleX:    1,
+		}
+		visualiser.Run()
+		return
+	}
+
+	// Continue from snapshot, its parameters replace the configuration
+	var snapshot *simulation.Snapshot
+	start := time.Duration(0)
+	if *restorePath != "" {
+		var err error
+		if snapshot, err = readSnapshot(*restorePath); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		config = snapshot.Config
+		start = snapshot.Time
+	}
+
+	fmt.Println("Starting sharding simulator:", config.ShardCount, " shards.")
+
+	fmt.Println("Random seed:", config.Seed)
+
+	// The visualiser follows the wall clock
+	var simulationClock clock.Clock = clock.NewRealClockAt(start)
+	if *virtual {
+		if !*headless {
+			fmt.Println("The virtual clock can only be used in headless mode.")
+			os.Exit(2)
+		}
+		simulationClock = clock.NewEventClockAt(start)
+	}
+
+	// Create beacon and shards
+	simulator := simulation.Simulation{}
+	if snapshot != nil {
+		if err := simulator.Restore(snapshot, simulationClock); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else {
+		simulator.Init(&config, simulationClock)
+	}
+
+	// Record events
+	finishTrace := func() {}
+	if *tracePath != "" {
+		file, err := os.Create(*tracePath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		log := trace.NewLog(file)
+		simulator.Record(log)
+
+		finishTrace = func() {
+			if err := log.Flush(); err != nil {
+				fmt.Println(err)
+			}
+			file.Close()
+		}
+	}
+
+	simulator.Start()
+
+	if *headless {
+		simulationClock.Advance(*duration)
+		simulator.Stop()
+		finishTrace()
+		simulator.PrintSummary()
+
+		if *stakePath != "" {
+			if err := writeFile(*stakePath, simulator.Beacon().Validators().WriteHistoryCSV); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		if *snapshotPath != "" {
+			snapshot := simulator.Snapshot()
+			if err := writeFile(*snapshotPath, snapshot.Write); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		dag := simulator.DAG()
+		if *dotPath != "" {
+			if err := writeFile(*dotPath, dag.WriteDOT); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		if *dagPath != "" {
+			if err := writeFile(*dagPath, dag.WriteJSON); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	// Start visualiser
+	visualiser := Visualiser{
+		simulation: &simulator,
+		config:     simulator.Config(),
+		viewShard:  0,
+		scaleX:     1,
+	}
+
+	visualiser.Run()
+	finishTrace()
+}
+
+// Read snapshot file
+func readSnapshot(path string) (*simulation.Snapshot, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return simulation.ReadSnapshot(file)
+}
+
+// Read events of trace file
+func readTrace(path string) ([]trace.Event, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events, err := trace.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return events, nil
+}
+
+// Create file at path and write its content
+func writeFile(path string, write func(w io.Writer) error) error {
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,602 @@
+/* See also examples on https://github.com/golang-ui/nuklear */
+package main
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v3.2-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/sindbach/nuklear/nk"
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/simulation"
+	"github.com/xlab/closer"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+const (
+	winWidth         = 1024
+	winHeight        = 800
+	maxVertexBuffer  = 100 * 1024 * 1024
+	maxElementBuffer = 100 * 1024 * 1024
+	chainRowHeight   = 150
+	chainRowOffsetY  = 75
+)
+
+var (
+	cTEXT           = nk.NkRgb(185, 185, 185)
+	cLINE           = nk.NkRgb(95, 95, 95)
+	cTXLINE         = nk.NkRgb(255, 255, 255)
+	cGENISIS        = nk.NkRgb(0, 204, 255)
+	cFINALISED      = nk.NkRgb(204, 255, 0)
+	cFINALISEDOTHER = nk.NkRgb(180, 180, 180)
+	cINVALID        = nk.NkRgb(255, 51, 0)
+	cCANONICAL      = nk.NkRgb(243, 243, 21)
+	cSTALE          = nk.NkRgb(194, 14, 213)
+	cPRUNED         = nk.NkRgb(95, 95, 95)
+)
+
+// Colour of every block status
+var statusColors = map[chain.Status]nk.Color{
+	chain.StatusGenesis:        cGENISIS,
+	chain.StatusFinalised:      cFINALISED,
+	chain.StatusFinalisedOther: cFINALISEDOTHER,
+	chain.StatusInvalid:        cINVALID,
+	chain.StatusCanonical:      cCANONICAL,
+	chain.StatusStale:          cSTALE,
+	chain.StatusPruned:         cPRUNED,
+}
+
+func init() {
+	runtime.LockOSThread()
+}
+
+// Shards shown by the visualiser, of a running simulation or a replayed trace.
+// Views are copies, so drawing does not race with the shard goroutines.
+type shardSource interface {
+	View(id int) *simulation.ShardView
+	Clock() clock.Clock
+	DAG() chain.DAG
+}
+
+type Visualiser struct {
+	simulation        *simulation.Simulation
+	replay            *simulation.Replay
+	lastFrame         time.Time
+	config            *simulation.Config
+	state             simulation.Command
+	viewShard         int32
+	shardView         *simulation.ShardView
+	font              *nk.UserFont
+	offSetX           float64
+	scaleX            float64
+	selectedNode      *chain.ChainBlock
+	selectedNodeChain int
+	selectedTX        *chain.Transaction
+}
+
+func (visualiser *Visualiser) Init() {
+}
+
+func (visualiser *Visualiser) view() shardSource {
+	if visualiser.replay != nil {
+		return visualiser.replay
+	}
+	return visualiser.simulation
+}
+
+func (visualiser *Visualiser) Run() {
+	////////////////////////////////////////////
+	if err := glfw.Init(); err != nil {
+		closer.Fatalln(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 2)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	win, err := glfw.CreateWindow(winWidth, winHeight, "Smart-Shard Visualiser", nil, nil)
+	if err != nil {
+		closer.Fatalln(err)
+	}
+	win.MakeContextCurrent()
+
+	width, height := win.GetSize()
+
+	if err := gl.Init(); err != nil {
+		closer.Fatalln("opengl: initialisation failed:", err)
+	}
+	gl.Viewport(0, 0, int32(width), int32(height))
+	ctx := nk.NkPlatformInit(win, nk.PlatformInstallCallbacks)
+
+	win.SetScrollCallback(func(win *glfw.Window, x, y float64) {
+		visualiser.offSetX = visualiser.offSetX + x
+		visualiser.scaleX = visualiser.scaleX - (y / 100)
+		if visualiser.scaleX < 0.5 {
+			visualiser.scaleX = 0.5
+		}
+	})
+
+	// Fonts
+	fontHandle := initFont(ctx)
+	visualiser.font = fontHandle
+	defer runtime.KeepAlive(fontHandle)
+
+	// Style
+	//ctx.Style().Window().
+
+	exitC := make(chan struct{}, 1)
+	doneC := make(chan struct{}, 1)
+	refreshC := make(chan bool, 1)
+
+	closer.Bind(func() {
+		close(exitC)
+		<-doneC
+	})
+
+	refreshC <- true
+	for {
+		select {
+		case <-exitC:
+			nk.NkPlatformShutdown()
+			glfw.Terminate()
+			if visualiser.simulation != nil {
+				visualiser.simulation.Stop()
+			}
+			close(doneC)
+			close(refreshC)
+			return
+		case <-refreshC:
+			if win.ShouldClose() {
+				close(exitC)
+				continue
+			}
+			glfw.PollEvents()
+			visualiser.gfxMain(win, ctx)
+			//time.Sleep(time.Second/10)
+			refreshC <- true
+		}
+	}
+}
+
+func (visualiser *Visualiser) gfxMain(win *glfw.Window, ctx *nk.Context) {
+
+	// Create GUI frame
+	nk.NkPlatformNewFrame()
+	width, height := win.GetSize()
+
+	// Play replay in real time
+	now := time.Now()
+	if visualiser.replay != nil && visualiser.state == simulation.Run && !visualiser.lastFrame.IsZero() {
+		visualiser.replay.Advance(now.Sub(visualiser.lastFrame))
+	}
+	visualiser.lastFrame = now
+
+	// View of the shown shard for this frame
+	shard := visualiser.viewShard + 1
+	visualiser.shardView = visualiser.view().View(int(shard))
+	if visualiser.selectedNode != nil {
+		if selected := visualiser.shardView.Chain(visualiser.selectedNodeChain).Search(visualiser.selectedNode.Block().Hash); selected != nil {
+			visualiser.selectedNode = selected
+		}
+	}
+
+	bounds := nk.NkRect(0, 0, float32(width), float32(height))
+	update := nk.NkBegin(ctx, "Shard Inspector", bounds, 0)
+
+	if update > 0 {
+		visualiser.drawLayout(win, ctx)
+	}
+	nk.NkEnd(ctx)
+
+	// Render
+	bg := make([]float32, 4)
+	nk.NkColorFv(bg, nk.NkRgba(50, 50, 50, 255))
+	gl.Viewport(0, 0, int32(width), int32(height))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	gl.ClearColor(bg[0], bg[1], bg[2], bg[3])
+	nk.NkPlatformRender(nk.AntiAliasingOn, maxVertexBuffer, maxElementBuffer)
+	win.SwapBuffers()
+}
+
+func initFont(ctx *nk.Context) *nk.UserFont {
+	atlas := nk.NewFontAtlas()
+	nk.NkFontStashBegin(&atlas)
+	sansFont := nk.NkFontAtlasAddDefault(atlas, 16, nil)
+	nk.NkFontStashEnd()
+	if sansFont != nil {
+		sansFontHandle := sansFont.Handle()
+		nk.NkStyleSetFont(ctx, sansFontHandle)
+		return sansFontHandle
+	}
+	return nil
+}
+
+func (visualiser *Visualiser) drawLayout(win *glfw.Window, ctx *nk.Context) {
+
+	_, height := win.GetSize()
+	canvas := nk.NkWindowGetCanvas(ctx)
+
+	toolbarHeight := float32(25)
+	paddingX := float32(15)
+	paddingY := float32(10)
+
+	// Draw Menu
+	nk.NkLayoutRowStatic(ctx, toolbarHeight, 100, 10)
+	{
+		// Start button
+		if nk.NkButtonLabel(ctx, "Start") > 0 {
+			visualiser.start()
+		}
+
+		if nk.NkButtonLabel(ctx, "Pause") > 0 {
+			visualiser.stop()
+		}
+
+		if nk.NkButtonLabel(ctx, "Pretty Print") > 0 {
+			visualiser.prettyPrint()
+		}
+
+		if nk.NkButtonLabel(ctx, "Export DAG") > 0 {
+			visualiser.exportDAG()
+		}
+
+		if nk.NkButtonLabel(ctx, "Snapshot") > 0 {
+			visualiser.saveSnapshot()
+		}
+
+		comboString := ""
+		for i := 1; i <= visualiser.config.ShardCount; i++ {
+			comboString = fmt.Sprint(comboString, fmt.Sprintf("Shard %d", i), "\x00")
+		}
+		nk.NkComboboxString(ctx, comboString, &visualiser.viewShard, int32(visualiser.config.ShardCount), 25, nk.NkVec2(150, 200))
+
+		if visualiser.replay != nil {
+			visualiser.drawReplayControls(ctx)
+		} else {
+			visualiser.drawSimulationControls(ctx)
+		}
+	}
+
+	winStartX := paddingX
+	winStartY := toolbarHeight + 2*paddingY
+
+	// Calculate  width
+	duration := visualiser.view().Clock().Now()
+	winWidth := duration.Seconds()*chain.PixelsPerSecond + 2*float64(paddingX)
+
+	nk.NkLayoutRowTemplateBegin(ctx, float32(height)-30-toolbarHeight)
+	nk.NkLayoutRowTemplatePushVariable(ctx, 320)
+	nk.NkLayoutRowTemplatePushStatic(ctx, 210)
+	nk.NkLayoutRowTemplateEnd(ctx)
+
+	if nk.NkGroupBegin(ctx, "", 0) > 0 {
+
+		nk.NkLayoutRowDynamic(ctx, float32(chainRowHeight), 1)
+
+		winStartX = 0 + float32(visualiser.offSetX) + 80
+
+		// Y position of every shard row, follows the scrollbar
+		rowStartY := make([]float32, visualiser.config.ShardCount+1)
+
+		for i := 1; i <= visualiser.config.ShardCount; i++ {
+
+			widthX := float32(winWidth)
+			widthY := float32(80)
+
+			rowBounds := nk.NkWidgetBounds(ctx)
+			winStartY = rowBounds.Y() + chainRowOffsetY
+			rowStartY[i] = winStartY
+
+			// Plot chain
+			genesisBlock := visualiser.shardView.Chain(i).GenesisBlock()
+			visualiser.drawChain(ctx, canvas, i, winStartX, winStartY, widthX, widthY, genesisBlock)
+		}
+
+		nk.NkLayoutRowDynamic(ctx, 25, 1)
+		text := string("Tip: Use mouse scroll to scale and move the x-as of block trees, use the scrollbar to browse shards.")
+		nk.NkText(ctx, text, int32(len(text)), nk.TextAlignLeft)
+
+		if visualiser.selectedNode != nil {
+
+			for _, tx := range visualiser.selectedNode.Block().TXIn {
+				blocksOut := visualiser.shardView.Chain(tx.SourceShard).GetChainBlock(tx)
+
+				for _, block := range blocksOut {
+
+					x0 := winStartX + (block.Coordinate().X * float32(visualiser.scaleX)) + 5
+					y0 := rowStartY[tx.SourceShard] + block.Coordinate().Y + 5
+					x1 := winStartX + (visualiser.selectedNode.Coordinate().X * float32(visualiser.scaleX)) + 5
+					y1 := rowStartY[tx.TargetShard] + visualiser.selectedNode.Coordinate().Y + 5
+
+					nk.NkStrokeLine(canvas, x0, y0, x1, y1, 1.0, cTXLINE)
+
+				}
+			}
+		}
+		nk.NkGroupEnd(ctx)
+	}
+	if nk.NkGroupBegin(ctx, "", 0) > 0 {
+		visualiser.drawBockInspector(ctx)
+		visualiser.drawTXInspector(ctx)
+		if visualiser.simulation != nil {
+			visualiser.drawStakeInspector(ctx)
+		}
+		nk.NkGroupEnd(ctx)
+	}
+}
+
+// Sliders changing the parameters of the running simulation
+func (visualiser *Visualiser) drawSimulationControls(ctx *nk.Context) {
+
+	forkProb := 1 - visualiser.config.ProbabilityBuildOnLongestChain
+
+	nk.NkLabel(ctx, fmt.Sprintf("Forks (%.0f%%):", forkProb*100), nk.TextAlignRight|nk.TextAlignMiddle)
+	newForkProb := nk.NkSlideFloat(ctx, 0, float32(forkProb), 0.5, 0.1)
+	if newForkProb != float32(forkProb) {
+		visualiser.whileStopped(func() {
+			visualiser.config.ProbabilityBuildOnLongestChain = float64(1 - newForkProb)
+		})
+	}
+
+	nk.NkLabel(ctx, "Finalise Speed:", nk.TextAlignRight|nk.TextAlignMiddle)
+	newSpeed := nk.NkSlideFloat(ctx, 1, float32(visualiser.config.FinalisationPeriod.Min), 8, 1)
+	if newSpeed != float32(visualiser.config.FinalisationPeriod.Min) {
+		visualiser.whileStopped(func() {
+			visualiser.config.FinalisationPeriod.Min = int(newSpeed)
+			visualiser.config.FinalisationPeriod.Max = int(newSpeed) + 2
+		})
+	}
+}
+
+// Step and seek buttons of the replayed trace
+func (visualiser *Visualiser) drawReplayControls(ctx *nk.Context) {
+
+	if nk.NkButtonLabel(ctx, "Step Back") > 0 {
+		visualiser.stop()
+		visualiser.selectedNode = nil
+		visualiser.replay.StepBack()
+	}
+
+	if nk.NkButtonLabel(ctx, "Step") > 0 {
+		visualiser.stop()
+		visualiser.replay.Step()
+	}
+
+	nk.NkLabel(ctx, fmt.Sprintf("Event %d/%d:", visualiser.replay.Position(), visualiser.replay.Len()), nk.TextAlignRight|nk.TextAlignMiddle)
+	position := nk.NkSlideInt(ctx, 0, int32(visualiser.replay.Position()), int32(visualiser.replay.Len()), 1)
+	if int(position) != visualiser.replay.Position() {
+		if int(position) < visualiser.replay.Position() {
+			visualiser.selectedNode = nil
+		}
+		visualiser.replay.Seek(int(position))
+	}
+}
+
+func (visualiser *Visualiser) drawBockInspector(ctx *nk.Context) {
+
+	nk.NkLayoutRowDynamic(ctx, float32(400), 1)
+
+	if visualiser.selectedNode != nil {
+
+		nk.NkGroupBegin(ctx, "Block Inspector", nk.WindowTitle|nk.WindowBorder)
+
+		nk.NkLayoutRowDynamic(ctx, 25, 1)
+
+		nk.NkLabelColored(ctx, "Hash:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+		nk.NkLabel(ctx, fmt.Sprintf(" %x", visualiser.selectedNode.Block().Hash), nk.TextAlignLeft|nk.TextAlignMiddle)
+
+		nk.NkLabelColored(ctx, "Proposer:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+		nk.NkLabel(ctx, fmt.Sprintf(" %s - slot %d", visualiser.selectedNode.Block().Validator, visualiser.selectedNode.Block().Slot), nk.TextAlignLeft|nk.TextAlignMiddle)
+
+		nk.NkLabelColored(ctx, "TX - IN:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+
+		for _, tx := range visualiser.selectedNode.Block().TXIn {
+			if nk.NkSelectLabel(ctx, fmt.Sprintf(" %x", tx.Hash), nk.TextAlignLeft|nk.TextAlignMiddle, visualiser.isSelectedTx(tx)) > 0 {
+				visualiser.selectedTX = tx
+			}
+		}
+
+		nk.NkLabelColored(ctx, "TX - OUT:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+
+		for _, tx := range visualiser.selectedNode.Block().TXOut {
+			if nk.NkSelectLabel(ctx, fmt.Sprintf("%x", tx.Hash), nk.TextAlignLeft|nk.TextAlignMiddle, visualiser.isSelectedTx(tx)) > 0 {
+				visualiser.selectedTX = tx
+			}
+		}
+
+		nk.NkGroupEnd(ctx)
+	}
+}
+
+func (visualiser *Visualiser) isSelectedTx(transaction *chain.Transaction) int32 {
+	if visualiser.selectedTX != nil && visualiser.selectedTX.Hash == transaction.Hash {
+		return 1
+	}
+	return 0
+}
+
+func (visualiser *Visualiser) drawTXInspector(ctx *nk.Context) {
+
+	nk.NkLayoutRowDynamic(ctx, float32(230), 1)
+
+	if visualiser.selectedTX != nil {
+
+		nk.NkGroupBegin(ctx, "TX Inspector", nk.WindowTitle|nk.WindowBorder)
+
+		nk.NkLayoutRowDynamic(ctx, 25, 1)
+
+		nk.NkLabelColored(ctx, "Hash:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+		nk.NkLabel(ctx, fmt.Sprintf(" %x", visualiser.selectedTX.Hash), nk.TextAlignLeft|nk.TextAlignMiddle)
+
+		nk.NkLabelColored(ctx, "Source Shard:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+		nk.NkLabel(ctx, fmt.Sprintf(" %x", visualiser.selectedTX.SourceShard), nk.TextAlignCentered|nk.TextAlignMiddle)
+
+		nk.NkLabelColored(ctx, "Target Shard:", nk.TextAlignCentered|nk.TextAlignMiddle, cTXLINE)
+		nk.NkLabel(ctx, fmt.Sprintf(" %x", visualiser.selectedTX.TargetShard), nk.TextAlignCentered|nk.TextAlignMiddle)
+
+		nk.NkGroupEnd(ctx)
+	}
+}
+
+func (visualiser *Visualiser) drawStakeInspector(ctx *nk.Context) {
+
+	nk.NkLayoutRowDynamic(ctx, float32(200), 1)
+
+	if nk.NkGroupBegin(ctx, "Committee Stake", nk.WindowTitle|nk.WindowBorder) > 0 {
+
+		nk.NkLayoutRowDynamic(ctx, 20, 2)
+
+		stakes := visualiser.simulation.ShardStakes()
+		for shard := 1; shard <= visualiser.config.ShardCount; shard++ {
+			nk.NkLabelColored(ctx, fmt.Sprintf("Shard %d:", shard), nk.TextAlignLeft|nk.TextAlignMiddle, cTXLINE)
+			nk.NkLabel(ctx, fmt.Sprintf("%d", stakes[shard]), nk.TextAlignRight|nk.TextAlignMiddle)
+		}
+
+		nk.NkGroupEnd(ctx)
+	}
+}
+
+func (visualiser *Visualiser) drawChain(ctx *nk.Context, canvas *nk.CommandBuffer, shard int, winStartX float32, winStartY float32, width float32, height float32, genisisBlock *chain.ChainBlock) {
+
+	// Root above genesis height, history is pruned
+	title := fmt.Sprintf("Shard %d", shard)
+	if genisisBlock.Height() > 0 {
+		title = fmt.Sprintf("Shard %d - pruned below height %d", shard, genisisBlock.Height())
+	}
+
+	nk.NkGroupBegin(ctx, title, nk.WindowBorder|nk.WindowTitle)
+	input := ctx.Input()
+	if genisisBlock.Height() > 0 {
+		visualiser.drawPrunedHistory(canvas, winStartX, winStartY, genisisBlock)
+	}
+	visualiser.drawBlock(canvas, input, shard, winStartX, winStartY, genisisBlock)
+	nk.NkGroupEnd(ctx)
+}
+
+// Dashed line from the start of the simulation up to the root of a pruned block tree
+func (visualiser *Visualiser) drawPrunedHistory(canvas *nk.CommandBuffer, winStartX float32, winStartY float32, root *chain.ChainBlock) {
+
+	x1 := winStartX + (root.Coordinate().X * float32(visualiser.scaleX)) + 5
+	y := winStartY + root.Coordinate().Y + 5
+
+	for x := winStartX + 5; x < x1; x += 8 {
+		nk.NkStrokeLine(canvas, x, y, minFloat(x+4, x1), y, 1.0, cPRUNED)
+	}
+}
+
+func minFloat(a float32, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (visualiser *Visualiser) drawBlock(canvas *nk.CommandBuffer, input *nk.Input, shard int, winStartX float32, winStartY float32, chainBlock *chain.ChainBlock) {
+
+	// Draw lines child blocks
+	for _, child := range chainBlock.Children() {
+		x0 := winStartX + (chainBlock.Coordinate().X * float32(visualiser.scaleX)) + 5
+		y0 := winStartY + chainBlock.Coordinate().Y + 5
+		x1 := winStartX + (child.Coordinate().X * float32(visualiser.scaleX)) + 5
+		y1 := winStartY + child.Coordinate().Y + 5
+
+		nk.NkStrokeLine(canvas, x0, y0, x1, y1, 1.0, cLINE)
+	}
+
+	// Draw current block
+	x := winStartX + (chainBlock.Coordinate().X * float32(visualiser.scaleX))
+	y := winStartY + chainBlock.Coordinate().Y
+	color := statusColors[chainBlock.Coordinate().Status]
+
+	c1 := nk.NkRect(x, y, 10.0, 10.0)
+	if visualiser.selectedNode != nil && reflect.DeepEqual(chainBlock.Block().Hash, visualiser.selectedNode.Block().Hash) {
+		nk.NkFillCircle(canvas, c1, color)
+		nk.NkStrokeCircle(canvas, c1, 2, color)
+	} else {
+		nk.NkStrokeCircle(canvas, c1, 2, color)
+	}
+
+	if nk.NkInputHasMouseClickDownInRect(input, nk.ButtonLeft, c1, 1) > 0 {
+		visualiser.selectedNode = chainBlock
+		visualiser.selectedNodeChain = shard
+	}
+
+	// Draw child blocks
+	for _, child := range chainBlock.Children() {
+		visualiser.drawBlock(canvas, input, shard, winStartX, winStartY, child)
+	}
+}
+
+func (visualiser *Visualiser) start() {
+	visualiser.state = simulation.Run
+	if visualiser.simulation != nil {
+		visualiser.simulation.Resume()
+	}
+	fmt.Println("START")
+}
+
+func (visualiser *Visualiser) stop() {
+	visualiser.state = simulation.Pause
+	if visualiser.simulation != nil {
+		visualiser.simulation.Pause()
+	}
+	fmt.Println("PAUSE")
+}
+
+func (visualiser *Visualiser) prettyPrint() {
+	shard := visualiser.shardView.ID()
+	visualiser.shardView.Chain(shard).PrettyPrint()
+}
+
+// Write block DAG of all shards to dag.dot and dag.json in the working directory
+func (visualiser *Visualiser) exportDAG() {
+	dag := visualiser.view().DAG()
+	if err := writeFile("dag.dot", dag.WriteDOT); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := writeFile("dag.json", dag.WriteJSON); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("EXPORTED DAG")
+}
+
+// Write state of the simulation to snapshot.json in the working directory, the simulation continues.
+func (visualiser *Visualiser) saveSnapshot() {
+
+	if visualiser.simulation == nil {
+		fmt.Println("Snapshots can only be taken of a running simulation.")
+		return
+	}
+
+	var snapshot simulation.Snapshot
+	visualiser.whileStopped(func() {
+		snapshot = visualiser.simulation.Snapshot()
+	})
+
+	if err := writeFile("snapshot.json", snapshot.Write); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("SAVED SNAPSHOT")
+}
+
+// Run f while the actors of the simulation are stopped, e.g. to change the config they read.
+// The simulation continues afterwards, paused if it was paused.
+func (visualiser *Visualiser) whileStopped(f func()) {
+
+	if visualiser.simulation == nil {
+		f()
+		return
+	}
+
+	visualiser.simulation.Stop()
+	f()
+	visualiser.simulation.Start()
+	if visualiser.state == simulation.Pause {
+		visualiser.simulation.Pause()
+	}
+}
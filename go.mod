@@ -0,0 +1,10 @@
+module github.com/sjoerdwels/Guaranteed-TX
+
+go 1.13
+
+require (
+	github.com/fatih/color v1.9.0
+	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7
+	github.com/go-gl/glfw v0.0.0-20190409004039-e6da0acd62b1
+	github.com/xlab/closer v0.0.0-20190328110542-03326addb7c2
+)
@@ -0,0 +1,242 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+type Beacon struct {
+	config       *Config
+	channels     *Communication
+	random       *rand.Rand
+	clock        clock.Clock
+	timer        <-chan time.Time
+	chains       []chain.Chain
+	finalisation chain.Finalisation
+	validators   ValidatorRegistry
+	metrics      *Metrics
+	keys         *Keyring
+	committee    []*beaconValidator
+	rounds       CommitteeStats
+	recorder     *trace.Recorder
+}
+
+func (beacon *Beacon) init() {
+
+	// Init chains
+	beacon.chains = make([]chain.Chain, beacon.config.ShardCount+1)
+	for i, _ := range beacon.chains {
+		beacon.chains[i] = chain.Chain{}
+		beacon.chains[i].Init(i, beacon.clock)
+		beacon.chains[i].SetOrphanTimeout(beacon.config.orphanTimeout())
+		beacon.chains[i].SetVerifier(beacon.keys)
+	}
+
+	// Init finalisation
+	beacon.finalisation = chain.Finalisation{
+		Height:         0,
+		Blocks:         nil,
+		InconsistentTX: make([]*chain.Transaction, 0),
+	}
+
+	// Init validators
+	beacon.validators.init(beacon.keys, beacon.config.InitialStake)
+
+	// Init beacon committee
+	beacon.initCommittee()
+
+	// Init finalisation timer
+	beacon.timer = beacon.clock.After(beacon.config.FinalisationPeriod.NextRandomTimePeriod(beacon.random))
+}
+
+// Record events of the beacon and its chains
+func (beacon *Beacon) setRecorder(recorder *trace.Recorder) {
+	beacon.recorder = recorder
+	for i := range beacon.chains {
+		beacon.chains[i].SetRecorder(recorder)
+	}
+}
+
+func (beacon *Beacon) run() {
+
+	fmt.Println("Launching beacon shard...")
+
+	state := Pause
+
+	for {
+
+		// While paused only commands and queries are handled, block until one arrives. Own finalisations are
+		// processed already.
+		if state == Pause {
+			select {
+			case command := <-beacon.channels.control[0]:
+				beacon.clock.Done()
+				switch *command {
+				case Run:
+					state = Run
+				case Exit:
+					return
+				}
+			case query := <-beacon.channels.queries[0]:
+				query()
+			case <-beacon.channels.finalisation[0]:
+				beacon.clock.Done()
+			}
+			continue
+		}
+
+		select {
+		case command := <-beacon.channels.control[0]:
+			beacon.clock.Done()
+			switch *command {
+			case Pause:
+				state = Pause
+			case Exit:
+				return
+			}
+
+		case query := <-beacon.channels.queries[0]:
+			query()
+
+		case block := <-beacon.channels.blocks[0]:
+			beacon.receiveBlock(block)
+			beacon.clock.Done()
+
+		case <-beacon.timer:
+			beacon.proposeFinalisation()
+			beacon.timer = beacon.clock.After(beacon.config.FinalisationPeriod.NextRandomTimePeriod(beacon.random))
+			beacon.clock.Done()
+
+		case <-beacon.channels.finalisation[0]:
+			beacon.Println("Received finalisation - all ready processed to prevent race conditions.")
+			beacon.clock.Done()
+		}
+	}
+}
+
+func (beacon *Beacon) receiveBlock(block *chain.Block) {
+
+	beacon.Println(fmt.Sprintf("Received block from  %d.",  block.Shard))
+	beacon.recorder.BlockReceived(block)
+
+	// Add block
+	beacon.chains[block.Shard].Insert(block)
+	beacon.deliver(block)
+}
+
+func (beacon *Beacon) proposeFinalisation() {
+
+	beacon.Println("Start finalisation process.")
+
+	// Finalisation fails without a supermajority of the beacon committee
+	finalisation := beacon.vote()
+	if finalisation == nil {
+
+		beacon.Println("BeaconChain finalisation skipped.")
+		return
+	}
+
+	beacon.recorder.FinalisationProposed(finalisation)
+	beacon.processFinalisation(finalisation)
+
+	beacon.channels.broadcastFinalisation(finalisation)
+}
+
+// Penalise shard committees for unprocessed incoming transactions, the penalties are signed with the finalisation
+func (beacon *Beacon) penalise(finalisation *chain.Finalisation) {
+	finalisation.Penalties = make([]int64, beacon.config.ShardCount+1)
+	for _, tx := range finalisation.InconsistentTX {
+		finalisation.Penalties[tx.TargetShard] += beacon.config.StakePenalty
+	}
+}
+
+func (beacon *Beacon) processFinalisation(finalisation *chain.Finalisation) {
+
+	// Finalise blocks
+	finalised := make([]*chain.Block, 0)
+	for _, block := range finalisation.Blocks {
+
+		// Collect newly finalised blocks
+		chainBlock := beacon.chains[block.Shard].Search(block.Hash)
+		for ; chainBlock != nil && !chainBlock.Finalised(); chainBlock = chainBlock.Parent() {
+			finalised = append(finalised, chainBlock.Block())
+		}
+
+		// Finalise blocks
+		beacon.chains[block.Shard].Finalise(block.Hash)
+	}
+	beacon.recordFinalisedTransactions(finalised)
+
+	// Finalise blocks in the views of the committee
+	for _, validator := range beacon.committee {
+		validator.finalise(finalisation)
+	}
+
+	// Slash stake
+	for shard, penalty := range finalisation.Penalties {
+		if penalty > 0 {
+			beacon.validators.Penalise(shard, penalty)
+		}
+	}
+	beacon.slashEquivocations()
+	beacon.metrics.RecordInconsistent(finalisation, beacon.clock.Now(), beacon.config.StakePenalty)
+	beacon.validators.record(finalisation.Height)
+
+	beacon.finalisation = *finalisation
+
+}
+
+// Slash validators that signed two blocks for one slot of their shard. Blocks are verified on insertion, so the
+// blocks prove the equivocation; slashing the full stake again has no effect.
+func (beacon *Beacon) slashEquivocations() {
+	for shard := 1; shard < len(beacon.chains); shard++ {
+		for _, equivocation := range beacon.chains[shard].Equivocations() {
+			if identity := beacon.keys.Identity(shard, equivocation.Validator); identity != nil {
+				beacon.validators.Slash(identity)
+			}
+		}
+	}
+}
+
+// Record finalisation of the transactions in newly finalised blocks, outgoing transactions before incoming ones
+// as a transaction can be finalised in both shards by the same finalisation.
+func (beacon *Beacon) recordFinalisedTransactions(blocks []*chain.Block) {
+
+	now := beacon.clock.Now()
+
+	for _, block := range blocks {
+		beacon.metrics.RecordSourceFinalised(block, now)
+	}
+	for _, block := range blocks {
+		for _, tx := range block.TXIn {
+			beacon.metrics.Record(tx, now)
+		}
+	}
+}
+
+// Block tree of shard i as seen by the beacon
+func (beacon *Beacon) Chain(i int) *chain.Chain {
+	return &beacon.chains[i]
+}
+
+// Last finalisation proposed by the beacon
+func (beacon *Beacon) Finalisation() *chain.Finalisation {
+	return &beacon.finalisation
+}
+
+// Validators and their stake
+func (beacon *Beacon) Validators() *ValidatorRegistry {
+	return &beacon.validators
+}
+
+func (beacon *Beacon) Println(a ...interface{}) {
+	if beacon.config.Verbose {
+		fmt.Printf("[beacon] ")
+		fmt.Println(a...)
+	}
+}
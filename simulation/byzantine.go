@@ -0,0 +1,288 @@
+package simulation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Behaviours of a byzantine shard committee
+const (
+	// TXin without TXout in the source shard
+	PhantomTXIn = "phantom-txin"
+	// TXin of a TXout in a stale branch of the source shard
+	StaleTXIn = "stale-txin"
+	// Two sibling blocks processing the same TXin
+	DoubleSpend = "double-spend"
+	// Two sibling blocks, each sent to a different half of the network
+	Equivocate = "equivocate"
+	// Block kept private until the shard publishes its next block
+	Withhold = "withhold"
+	// Block signed by a committee member which is not the proposer of the slot
+	Impersonate = "impersonate"
+)
+
+// Names of all byzantine behaviours
+var Behaviours = []string{PhantomTXIn, StaleTXIn, DoubleSpend, Equivocate, Withhold, Impersonate}
+
+// Byzantine shard committee, every block it produces misbehaves with probability,
+// in one of its behaviours picked at random.
+type ByzantineShard struct {
+	Shard       int      `json:"shard"`
+	Behaviours  []string `json:"behaviours"`
+	Probability float64  `json:"probability"`
+}
+
+// Format as "shard:behaviours:probability", e.g. "2:phantom-txin,withhold:0.5"
+func (byzantine *ByzantineShard) String() string {
+	return fmt.Sprintf("%d:%s:%v", byzantine.Shard, strings.Join(byzantine.Behaviours, ","), byzantine.Probability)
+}
+
+func (byzantine *ByzantineShard) validate(shardCount int) error {
+	name := fmt.Sprintf("byzantine %s", byzantine.String())
+	if byzantine.Shard < 1 || byzantine.Shard > shardCount {
+		return fmt.Errorf("config: %s: shard must be between 1 and %d", name, shardCount)
+	}
+	if len(byzantine.Behaviours) == 0 {
+		return fmt.Errorf("config: %s: at least 1 behaviour is required", name)
+	}
+	for _, behaviour := range byzantine.Behaviours {
+		if !knownBehaviour(behaviour) {
+			return fmt.Errorf("config: %s: unknown behaviour %q, expected %s", name, behaviour, strings.Join(Behaviours, ", "))
+		}
+	}
+	if byzantine.Probability < 0 || byzantine.Probability > 1 {
+		return fmt.Errorf("config: %s: probability must be between 0 and 1", name)
+	}
+	return nil
+}
+
+func knownBehaviour(name string) bool {
+	for _, behaviour := range Behaviours {
+		if behaviour == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Byzantine shard committees, shards not listed are honest
+type ByzantineShards []ByzantineShard
+
+func (shards *ByzantineShards) String() string {
+	list := make([]string, len(*shards))
+	for i := range *shards {
+		list[i] = (*shards)[i].String()
+	}
+	return strings.Join(list, " ")
+}
+
+// Add byzantine shard from "shard:behaviours:probability", used by the repeatable command line flag
+func (shards *ByzantineShards) Set(value string) error {
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected shard:behaviours:probability")
+	}
+
+	shard, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return fmt.Errorf("expected shard:behaviours:probability: %v", err)
+	}
+	probability, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+	if err != nil {
+		return fmt.Errorf("expected shard:behaviours:probability: %v", err)
+	}
+
+	byzantine := ByzantineShard{Shard: shard, Probability: probability}
+	for _, behaviour := range strings.Split(parts[1], ",") {
+		behaviour = strings.TrimSpace(behaviour)
+		if !knownBehaviour(behaviour) {
+			return fmt.Errorf("unknown behaviour %q, expected %s", behaviour, strings.Join(Behaviours, ", "))
+		}
+		byzantine.Behaviours = append(byzantine.Behaviours, behaviour)
+	}
+
+	*shards = append(*shards, byzantine)
+	return nil
+}
+
+// Byzantine committee of shard, nil for an honest shard
+func (shards ByzantineShards) shard(id int) *ByzantineShard {
+	for i := range shards {
+		if shards[i].Shard == id {
+			return &shards[i]
+		}
+	}
+	return nil
+}
+
+func (shards ByzantineShards) validate(shardCount int) error {
+	seen := make(map[int]bool)
+	for i := range shards {
+		if err := shards[i].validate(shardCount); err != nil {
+			return err
+		}
+		if seen[shards[i].Shard] {
+			return fmt.Errorf("config: byzantine: shard %d listed more than once", shards[i].Shard)
+		}
+		seen[shards[i].Shard] = true
+	}
+	return nil
+}
+
+// Publish block of a byzantine shard with one of its behaviours, the block is signed by the caller.
+// Behaviours lacking material, e.g. a stale TXout or transactions to tell siblings apart, publish the honest block.
+func (shard *Shard) misbehave(block chain.Block) {
+
+	behaviour := shard.byzantine.Behaviours[shard.random.Intn(len(shard.byzantine.Behaviours))]
+
+	switch behaviour {
+	case PhantomTXIn:
+		block.TXIn = append(block.TXIn, shard.phantomTX())
+		shard.sign(&block)
+		shard.publishBlock(block)
+
+	case StaleTXIn:
+		stale := shard.staleTXOut()
+		if len(stale) == 0 {
+			shard.publishBlock(block)
+			return
+		}
+		block.TXIn = append(block.TXIn, stale[shard.random.Intn(len(stale))])
+		shard.sign(&block)
+		shard.publishBlock(block)
+
+	case DoubleSpend:
+		if len(block.TXIn) == 0 || len(block.TXOut) == 0 {
+			shard.publishBlock(block)
+			return
+		}
+		sibling := shard.sibling(block)
+		sibling.TXIn = block.TXIn
+		shard.sign(&sibling)
+		shard.publishBlock(block)
+		shard.publishBlock(sibling)
+
+	case Equivocate:
+		if len(block.TXIn) == 0 && len(block.TXOut) == 0 {
+			shard.publishBlock(block)
+			return
+		}
+		sibling := shard.sibling(block)
+		shard.sign(&sibling)
+
+		// Beacon and even shards receive the block, odd shards its sibling, the shard both
+		halves := [][]int{{shard.id}, {shard.id}}
+		for actor := 0; actor <= shard.config.ShardCount; actor++ {
+			if actor != shard.id {
+				halves[actor%2] = append(halves[actor%2], actor)
+			}
+		}
+		shard.releaseWithheld()
+		shard.recorder.BlockProduced(&block)
+		shard.channels.multicastBlock(shard.id, halves[0], block)
+		shard.recorder.BlockProduced(&sibling)
+		shard.channels.multicastBlock(shard.id, halves[1], sibling)
+
+	case Withhold:
+		shard.recorder.BlockProduced(&block)
+		shard.withheld = append(shard.withheld, block)
+		shard.channels.multicastBlock(shard.id, []int{shard.id}, block)
+
+	case Impersonate:
+		committee := shard.keys.Committee(shard.id)
+		proposer := shard.keys.Proposer(shard.id, block.Slot)
+		if len(committee) == 1 {
+			shard.publishBlock(block)
+			return
+		}
+		impostor := committee[shard.random.Intn(len(committee))]
+		for impostor == proposer {
+			impostor = committee[shard.random.Intn(len(committee))]
+		}
+		impostor.sign(&block)
+		shard.publishBlock(block)
+	}
+
+	shard.misbehaved[behaviour]++
+}
+
+// Publish withheld blocks followed by block
+func (shard *Shard) publishBlock(block chain.Block) {
+	shard.releaseWithheld()
+	shard.recorder.BlockProduced(&block)
+	shard.channels.broadcastBlock(shard.id, block)
+}
+
+// Broadcast withheld blocks in the order they were produced, the shard already has them
+func (shard *Shard) releaseWithheld() {
+	for _, block := range shard.withheld {
+		shard.channels.broadcastBlock(shard.id, block)
+	}
+	shard.withheld = nil
+}
+
+// Block on the same parent and slot without transactions, unsigned. It differs from block if block has transactions.
+func (shard *Shard) sibling(block chain.Block) chain.Block {
+	return chain.Block{
+		Shard:      block.Shard,
+		Hash:       []byte{},
+		ParentHash: block.ParentHash,
+		Slot:       block.Slot,
+		TXIn:       []*chain.Transaction{},
+		TXOut:      []*chain.Transaction{},
+		Validator:  block.Validator,
+	}
+}
+
+// Transaction of a random other shard which it never created
+func (shard *Shard) phantomTX() *chain.Transaction {
+
+	shardRange := shard.config.ShardRange()
+	source := shardRange.NextRandomInt(shard.random)
+	for source == shard.id {
+		source = shardRange.NextRandomInt(shard.random)
+	}
+
+	tx := chain.Transaction{
+		SourceShard: source,
+		TargetShard: shard.id,
+		Data:        fmt.Sprintf("phantom %d-%x", source, shard.random.Uint32()),
+		Created:     shard.clock.Now(),
+	}
+	tx.SetHash()
+	return &tx
+}
+
+// Transactions to this shard in stale blocks of other shards, not in their canonical chain
+func (shard *Shard) staleTXOut() []*chain.Transaction {
+
+	stale := make([]*chain.Transaction, 0)
+	for i := 1; i <= shard.config.ShardCount; i++ {
+		if i == shard.id {
+			continue
+		}
+
+		blockTree := &shard.chains[i]
+		canonical := chain.NewTxSet(blockTree.GetLongestChainTXOutList())
+
+		var walk func(chainBlock *chain.ChainBlock)
+		walk = func(chainBlock *chain.ChainBlock) {
+			if !blockTree.BlockInLongestChain(chainBlock) {
+				for _, tx := range chainBlock.Block().TXOut {
+					if tx.TargetShard == shard.id && !canonical.Contains(tx) {
+						stale = append(stale, tx)
+					}
+				}
+			}
+			for _, child := range chainBlock.Children() {
+				walk(child)
+			}
+		}
+		walk(blockTree.LastFinalisedBlock())
+	}
+	return stale
+}
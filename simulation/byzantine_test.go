@@ -0,0 +1,104 @@
+package simulation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func TestByzantineShardsSet(t *testing.T) {
+
+	shards := ByzantineShards{}
+	if err := shards.Set("2:phantom-txin,withhold:0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 1 || shards[0].Shard != 2 || len(shards[0].Behaviours) != 2 || shards[0].Probability != 0.5 {
+		t.Errorf("unexpected byzantine shard %s", shards.String())
+	}
+	if shards.shard(2) != &shards[0] || shards.shard(1) != nil {
+		t.Errorf("expected shard 2 byzantine and shard 1 honest")
+	}
+
+	for _, value := range []string{"2:phantom-txin", "x:withhold:0.5", "2:lie:0.5", "2:withhold:high"} {
+		if err := shards.Set(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+
+	config := DefaultConfig()
+	config.Byzantine = ByzantineShards{{Shard: 2, Behaviours: []string{Withhold}, Probability: 0.5}, {Shard: 2, Behaviours: []string{Equivocate}, Probability: 0.5}}
+	if err := config.Validate(); err == nil {
+		t.Errorf("expected error for shard listed twice")
+	}
+	config.Byzantine = ByzantineShards{{Shard: 5, Behaviours: []string{Withhold}, Probability: 0.5}}
+	if err := config.Validate(); err == nil {
+		t.Errorf("expected error for unknown shard")
+	}
+}
+
+// Blocks of a byzantine shard never lead to the finalisation of inconsistent transactions.
+func TestByzantineSimulation(t *testing.T) {
+
+	for _, behaviour := range Behaviours {
+
+		config := DefaultConfig()
+		config.Seed = 1
+		config.DebugShard = 0
+		config.Byzantine = ByzantineShards{{Shard: 2, Behaviours: []string{behaviour}, Probability: 0.3}}
+
+		eventClock := clock.NewEventClock()
+		simulation := Simulation{}
+		simulation.Init(&config, eventClock)
+		simulation.Start()
+		eventClock.Advance(5 * time.Minute)
+		simulation.Stop()
+
+		if simulation.Shard(2).misbehaved[behaviour] == 0 {
+			t.Errorf("%s: shard 2 never misbehaved", behaviour)
+		}
+
+		// Misbehaviour is attributable to the signing validators
+		switch behaviour {
+		case DoubleSpend, Equivocate:
+			if len(simulation.Shard(2).Chain(2).Equivocations()) == 0 {
+				t.Errorf("%s: no equivocating validator of shard 2", behaviour)
+			}
+		case Impersonate:
+			if simulation.Beacon().Chain(2).Rejected() == 0 || simulation.Shard(1).Chain(2).Rejected() == 0 {
+				t.Errorf("%s: impersonated blocks of shard 2 not rejected", behaviour)
+			}
+		}
+
+		// The beacon slashes every equivocation it sees, it receives both blocks of a double spend
+		equivocations := simulation.Beacon().Chain(2).Equivocations()
+		if behaviour == DoubleSpend && len(equivocations) == 0 {
+			t.Errorf("%s: no equivocation seen by the beacon", behaviour)
+		}
+		for _, equivocation := range equivocations {
+			if balance := simulation.Beacon().Validators().Balance(simulation.keys.Identity(2, equivocation.Validator)); balance != 0 {
+				t.Errorf("%s: equivocating validator %s keeps stake %d", behaviour, equivocation.Validator, balance)
+			}
+		}
+
+		for shard := 1; shard <= config.ShardCount; shard++ {
+			checkFinalisedPath(t, fmt.Sprintf("%s beacon shard %d", behaviour, shard), simulation.Beacon().Chain(shard), false)
+			checkFinalisedPath(t, fmt.Sprintf("%s shard %d", behaviour, shard), simulation.Shard(shard).Chain(shard), true)
+
+			// Every finalised TXIn has a matching finalised TXOut, and is processed once
+			processed := make(map[string]bool)
+			_, txIn := finalisedTransactions(simulation.Beacon().Chain(shard))
+			for _, tx := range txIn {
+				txOut, _ := finalisedTransactions(simulation.Beacon().Chain(tx.SourceShard))
+				if !txOut[tx.Hash] || tx.TargetShard != shard {
+					t.Fatalf("%s: finalised TXIn of shard %d has no finalised TXOut in shard %d", behaviour, shard, tx.SourceShard)
+				}
+				if processed[tx.Hash] {
+					t.Fatalf("%s: TXIn finalised twice in shard %d", behaviour, shard)
+				}
+				processed[tx.Hash] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package simulation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Kinds of incoming transactions a shard can process
+const (
+	// TXout in the canonical chain of the source shard, not finalised yet
+	PendingTX = "pending"
+	// TXout finalised in the source shard and listed as inconsistent by the beacon, unprocessed ones are penalised
+	FinalisedTX = "finalised"
+)
+
+// Names of all kinds of incoming transactions
+var TXTypes = []string{PendingTX, FinalisedTX}
+
+// Censoring shard, never processes incoming transactions of the sources and types.
+// No sources means all source shards, no types means all types.
+type CensoringShard struct {
+	Shard   int      `json:"shard"`
+	Sources []int    `json:"sources,omitempty"`
+	Types   []string `json:"types,omitempty"`
+}
+
+// Format as "shard:sources:types", e.g. "2:1,3:finalised"
+func (censoring *CensoringShard) String() string {
+	sources := make([]string, len(censoring.Sources))
+	for i, source := range censoring.Sources {
+		sources[i] = strconv.Itoa(source)
+	}
+	if len(sources) == 0 {
+		sources = []string{"all"}
+	}
+	types := censoring.Types
+	if len(types) == 0 {
+		types = []string{"all"}
+	}
+	return fmt.Sprintf("%d:%s:%s", censoring.Shard, strings.Join(sources, ","), strings.Join(types, ","))
+}
+
+// Whether the shard censors the incoming transaction of the type
+func (censoring *CensoringShard) censors(tx *chain.Transaction, txType string) bool {
+
+	source := len(censoring.Sources) == 0
+	for _, censored := range censoring.Sources {
+		if censored == tx.SourceShard {
+			source = true
+		}
+	}
+
+	kind := len(censoring.Types) == 0
+	for _, censored := range censoring.Types {
+		if censored == txType {
+			kind = true
+		}
+	}
+
+	return source && kind
+}
+
+func (censoring *CensoringShard) validate(shardCount int) error {
+	name := fmt.Sprintf("censoring %s", censoring.String())
+	if censoring.Shard < 1 || censoring.Shard > shardCount {
+		return fmt.Errorf("config: %s: shard must be between 1 and %d", name, shardCount)
+	}
+	for _, source := range censoring.Sources {
+		if source < 1 || source > shardCount || source == censoring.Shard {
+			return fmt.Errorf("config: %s: source %d must be another shard between 1 and %d", name, source, shardCount)
+		}
+	}
+	for _, txType := range censoring.Types {
+		if !knownTXType(txType) {
+			return fmt.Errorf("config: %s: unknown type %q, expected %s", name, txType, strings.Join(TXTypes, ", "))
+		}
+	}
+	return nil
+}
+
+func knownTXType(name string) bool {
+	for _, txType := range TXTypes {
+		if txType == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Censoring shards, shards not listed process all incoming transactions
+type CensoringShards []CensoringShard
+
+func (shards *CensoringShards) String() string {
+	list := make([]string, len(*shards))
+	for i := range *shards {
+		list[i] = (*shards)[i].String()
+	}
+	return strings.Join(list, " ")
+}
+
+// Add censoring shard from "shard:sources:types", "all" for all sources or types, used by the repeatable command line flag
+func (shards *CensoringShards) Set(value string) error {
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected shard:sources:types")
+	}
+
+	shard, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return fmt.Errorf("expected shard:sources:types: %v", err)
+	}
+
+	censoring := CensoringShard{Shard: shard}
+	if sources := strings.TrimSpace(parts[1]); sources != "all" {
+		for _, value := range strings.Split(sources, ",") {
+			source, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("expected sources as 1,3 or all: %v", err)
+			}
+			censoring.Sources = append(censoring.Sources, source)
+		}
+	}
+	if types := strings.TrimSpace(parts[2]); types != "all" {
+		for _, txType := range strings.Split(types, ",") {
+			txType = strings.TrimSpace(txType)
+			if !knownTXType(txType) {
+				return fmt.Errorf("unknown type %q, expected %s or all", txType, strings.Join(TXTypes, ", "))
+			}
+			censoring.Types = append(censoring.Types, txType)
+		}
+	}
+
+	*shards = append(*shards, censoring)
+	return nil
+}
+
+// Censoring strategy of shard, nil for a shard processing all incoming transactions
+func (shards CensoringShards) shard(id int) *CensoringShard {
+	for i := range shards {
+		if shards[i].Shard == id {
+			return &shards[i]
+		}
+	}
+	return nil
+}
+
+func (shards CensoringShards) validate(shardCount int) error {
+	seen := make(map[int]bool)
+	for i := range shards {
+		if err := shards[i].validate(shardCount); err != nil {
+			return err
+		}
+		if seen[shards[i].Shard] {
+			return fmt.Errorf("config: censoring: shard %d listed more than once", shards[i].Shard)
+		}
+		seen[shards[i].Shard] = true
+	}
+	return nil
+}
+
+// Incoming transactions the shard is willing to process, censored ones are counted
+func (shard *Shard) uncensored(txIn []*chain.Transaction) []*chain.Transaction {
+
+	finalised := chain.NewTxSet(shard.finalisation.InconsistentTX)
+
+	allowed := make([]*chain.Transaction, 0, len(txIn))
+	for _, tx := range txIn {
+		txType := PendingTX
+		if finalised.Contains(tx) {
+			txType = FinalisedTX
+		}
+
+		if shard.censoring.censors(tx, txType) {
+			shard.censored[tx.Hash] = true
+		} else {
+			allowed = append(allowed, tx)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,111 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func TestCensoringShardsSet(t *testing.T) {
+
+	shards := CensoringShards{}
+	if err := shards.Set("2:1,3:finalised"); err != nil {
+		t.Fatal(err)
+	}
+	if err := shards.Set("4:all:all"); err != nil {
+		t.Fatal(err)
+	}
+	if shards.String() != "2:1,3:finalised 4:all:all" {
+		t.Errorf("unexpected censoring shards %s", shards.String())
+	}
+	if shards.shard(2) != &shards[0] || shards.shard(1) != nil {
+		t.Errorf("expected shard 2 censoring and shard 1 not")
+	}
+
+	for _, value := range []string{"2:1", "x:1:all", "2:x:all", "2:1:lie"} {
+		if err := shards.Set(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+
+	config := DefaultConfig()
+	for _, censoring := range []CensoringShards{
+		{{Shard: 2}, {Shard: 2, Sources: []int{1}}},
+		{{Shard: 5}},
+		{{Shard: 2, Sources: []int{2}}},
+		{{Shard: 2, Sources: []int{5}}},
+	} {
+		config.Censoring = censoring
+		if err := config.Validate(); err == nil {
+			t.Errorf("expected error for censoring %s", censoring.String())
+		}
+	}
+}
+
+func TestCensors(t *testing.T) {
+
+	fromOne := &chain.Transaction{SourceShard: 1, TargetShard: 2}
+	fromThree := &chain.Transaction{SourceShard: 3, TargetShard: 2}
+
+	tests := []struct {
+		censoring CensoringShard
+		tx        *chain.Transaction
+		txType    string
+		censors   bool
+	}{
+		{CensoringShard{Shard: 2}, fromOne, PendingTX, true},
+		{CensoringShard{Shard: 2, Sources: []int{1}}, fromOne, FinalisedTX, true},
+		{CensoringShard{Shard: 2, Sources: []int{1}}, fromThree, FinalisedTX, false},
+		{CensoringShard{Shard: 2, Types: []string{FinalisedTX}}, fromThree, FinalisedTX, true},
+		{CensoringShard{Shard: 2, Types: []string{FinalisedTX}}, fromThree, PendingTX, false},
+		{CensoringShard{Shard: 2, Sources: []int{3}, Types: []string{PendingTX}}, fromOne, PendingTX, false},
+	}
+
+	for _, test := range tests {
+		if censors := test.censoring.censors(test.tx, test.txType); censors != test.censors {
+			t.Errorf("censoring %s of %s TX from shard %d: expected %v", test.censoring.String(), test.txType, test.tx.SourceShard, test.censors)
+		}
+	}
+}
+
+// Transactions of a censored source shard are never processed, they stay inconsistent and drain stake.
+func TestCensoringSimulation(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 1
+	config.DebugShard = 0
+	config.Censoring = CensoringShards{{Shard: 2, Sources: []int{1}}}
+
+	eventClock := clock.NewEventClock()
+	simulation := Simulation{}
+	simulation.Init(&config, eventClock)
+	simulation.Start()
+	eventClock.Advance(5 * time.Minute)
+	simulation.Stop()
+
+	if len(simulation.Shard(2).censored) == 0 {
+		t.Fatalf("shard 2 never censored a transaction")
+	}
+
+	for _, blockTree := range []*chain.Chain{simulation.Beacon().Chain(2), simulation.Shard(2).Chain(2)} {
+		for chainBlock := blockTree.LastFinalisedBlock(); chainBlock != nil; chainBlock = chainBlock.Parent() {
+			for _, tx := range chainBlock.Block().TXIn {
+				if tx.SourceShard == 1 {
+					t.Fatalf("shard 2 processed a TX of shard 1")
+				}
+			}
+		}
+	}
+
+	censored := simulation.metrics.Inconsistency(1, 2)
+	if censored.Processed.Count != 0 || censored.Unprocessed == 0 || censored.Penalty == 0 {
+		t.Errorf("expected unprocessed TX and penalty from shard 1 to 2, got %+v", censored)
+	}
+
+	honest := simulation.metrics.Inconsistency(3, 2)
+	if honest.Processed.Count == 0 || honest.Penalty >= censored.Penalty {
+		t.Errorf("expected TX from shard 3 to 2 processed with less penalty, got %+v", honest)
+	}
+}
@@ -0,0 +1,207 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Beacon validator voting on finalisations, computed from its own view of the shard chains.
+// Blocks arriving at the beacon become visible to the validator after its view lag.
+type beaconValidator struct {
+	identity *Identity
+	chains   []chain.Chain
+	pending  []pendingBlock
+}
+
+// Block arrived at the beacon, not yet seen by the validator
+type pendingBlock struct {
+	block   *chain.Block
+	visible time.Duration
+}
+
+// Outcome of the finalisation rounds of the beacon committee
+type CommitteeStats struct {
+	Rounds int `json:"rounds"`
+	// Rounds in which a finalisation reached a supermajority
+	Finalised int `json:"finalised"`
+	// Rounds with a supermajority of votes cast, but split over different finalisations
+	Split int `json:"split"`
+	// Votes not cast by offline validators, and by validators missing finalised blocks
+	Absent int `json:"absent"`
+	Behind int `json:"behind"`
+}
+
+// Rounds failing because less than a supermajority of the committee voted
+func (stats *CommitteeStats) TooFewVotes() int {
+	return stats.Rounds - stats.Finalised - stats.Split
+}
+
+// More than two thirds of the committee
+func supermajority(votes int, committeeSize int) bool {
+	return 3*votes > 2*committeeSize
+}
+
+// Beacon committee with the identities of the keyring, every validator views the shard chains from genesis
+func (beacon *Beacon) initCommittee() {
+
+	identities := beacon.keys.Committee(0)
+	beacon.committee = make([]*beaconValidator, len(identities))
+
+	for i, identity := range identities {
+		validator := &beaconValidator{
+			identity: identity,
+			chains:   make([]chain.Chain, beacon.config.ShardCount+1),
+			pending:  make([]pendingBlock, 0),
+		}
+		for j := range validator.chains {
+			validator.chains[j].Init(j, beacon.clock)
+			validator.chains[j].SetOrphanTimeout(beacon.config.orphanTimeout())
+			validator.chains[j].SetVerifier(beacon.keys)
+		}
+		beacon.committee[i] = validator
+	}
+}
+
+// Pass block arrived at the beacon to every validator, after a random view lag
+func (beacon *Beacon) deliver(block *chain.Block) {
+
+	now := beacon.clock.Now()
+	for _, validator := range beacon.committee {
+		validator.add(pendingBlock{block: block, visible: now + beacon.config.BeaconViewLag.NextRandomTimePeriod(beacon.random)})
+	}
+}
+
+// Every validator online computes the finalisation from its own view and votes for it. The finalisation with most
+// votes, the first on a tie, is returned with its votes if it reaches a supermajority of the committee, otherwise nil.
+func (beacon *Beacon) vote() *chain.Finalisation {
+
+	now := beacon.clock.Now()
+	beacon.rounds.Rounds++
+
+	candidates := make([]*chain.Finalisation, 0)
+	index := make(map[string]int)
+	cast := 0
+
+	for _, validator := range beacon.committee {
+
+		// Validators are online with the finalisation probability
+		if beacon.random.Float64() > beacon.config.FinalisationProbability {
+			beacon.rounds.Absent++
+			continue
+		}
+
+		validator.catchUp(now, &beacon.finalisation)
+		if !validator.synced(&beacon.finalisation) {
+			beacon.rounds.Behind++
+			continue
+		}
+
+		candidate := chain.CalculateFinalisation(validator.chains, &beacon.finalisation)
+		beacon.penalise(&candidate)
+		hash := string(candidate.Hash())
+		i, ok := index[hash]
+		if !ok {
+			i = len(candidates)
+			index[hash] = i
+			candidates = append(candidates, &candidate)
+		}
+		candidates[i].Sign(validator.identity.Name, validator.identity.privateKey)
+		cast++
+	}
+
+	var finalisation *chain.Finalisation
+	for _, candidate := range candidates {
+		if finalisation == nil || len(candidate.Votes) > len(finalisation.Votes) {
+			finalisation = candidate
+		}
+	}
+
+	if finalisation == nil || !supermajority(len(finalisation.Votes), len(beacon.committee)) {
+		if supermajority(cast, len(beacon.committee)) {
+			beacon.rounds.Split++
+		}
+		return nil
+	}
+
+	beacon.rounds.Finalised++
+	return finalisation
+}
+
+// Outcome of the finalisation rounds so far
+func (beacon *Beacon) CommitteeStats() CommitteeStats {
+	return beacon.rounds
+}
+
+// Queue block in order of visibility
+func (validator *beaconValidator) add(pending pendingBlock) {
+	i := sort.Search(len(validator.pending), func(i int) bool { return validator.pending[i].visible > pending.visible })
+	validator.pending = append(validator.pending, pendingBlock{})
+	copy(validator.pending[i+1:], validator.pending[i:])
+	validator.pending[i] = pending
+}
+
+// Insert the blocks visible at time now, blocks of the finalisation that arrive late are finalised.
+func (validator *beaconValidator) catchUp(now time.Duration, finalisation *chain.Finalisation) {
+
+	visible := 0
+	for visible < len(validator.pending) && validator.pending[visible].visible <= now {
+		block := validator.pending[visible].block
+		validator.chains[block.Shard].Insert(block)
+		visible++
+	}
+	validator.pending = validator.pending[visible:]
+
+	if visible > 0 {
+		validator.finalise(finalisation)
+	}
+}
+
+// Finalise the blocks of the finalisation in the view, blocks not seen yet are finalised when they arrive
+func (validator *beaconValidator) finalise(finalisation *chain.Finalisation) {
+	for i := range finalisation.Blocks {
+		if block := &finalisation.Blocks[i]; !validator.finalised(block) {
+			validator.chains[block.Shard].Finalise(block.Hash)
+		}
+	}
+}
+
+// Whether the block is the last finalised block of its shard in the view
+func (validator *beaconValidator) finalised(block *chain.Block) bool {
+	return string(validator.chains[block.Shard].LastFinalisedBlock().Block().Hash) == string(block.Hash)
+}
+
+// Whether the view has finalised every block of the finalisation, so it can compute the next one
+func (validator *beaconValidator) synced(finalisation *chain.Finalisation) bool {
+	for i := range finalisation.Blocks {
+		if !validator.finalised(&finalisation.Blocks[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify the finalisation carries valid votes of a supermajority of the beacon committee
+func (keyring *Keyring) VerifyFinalisation(finalisation *chain.Finalisation) error {
+
+	committee := keyring.committees[0]
+	voted := make(map[string]bool)
+
+	for _, vote := range finalisation.Votes {
+		voter := keyring.Identity(0, vote.Validator)
+		if voter == nil || voted[vote.Validator] {
+			return fmt.Errorf("finalisation %d: unknown or repeated voter %s", finalisation.Height, vote.Validator)
+		}
+		if err := finalisation.VerifyVote(vote, voter.PublicKey); err != nil {
+			return err
+		}
+		voted[vote.Validator] = true
+	}
+
+	if !supermajority(len(voted), len(committee)) {
+		return fmt.Errorf("finalisation %d: %d of %d votes, no supermajority", finalisation.Height, len(voted), len(committee))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func TestKeyringVerifyFinalisation(t *testing.T) {
+
+	keyring := Keyring{}
+	keyring.init(2, 4, 4, 1)
+	committee := keyring.Committee(0)
+
+	newFinalisation := func(voters ...*Identity) *chain.Finalisation {
+		finalisation := &chain.Finalisation{Height: 1, Blocks: []chain.Block{{Shard: 1, Hash: []byte("block")}}}
+		for _, voter := range voters {
+			finalisation.Sign(voter.Name, voter.privateKey)
+		}
+		return finalisation
+	}
+
+	if err := keyring.VerifyFinalisation(newFinalisation(committee[0], committee[1], committee[2])); err != nil {
+		t.Errorf("expected finalisation with 3 of 4 votes accepted: %v", err)
+	}
+
+	// Vote of a shard validator, and a vote signed with the key of another validator
+	shardVote := newFinalisation(committee[0], committee[1], keyring.Committee(1)[0])
+	forged := newFinalisation(committee[0], committee[1], committee[3])
+	forged.Votes[2].Validator = committee[2].Name
+
+	for name, finalisation := range map[string]*chain.Finalisation{
+		"too few votes": newFinalisation(committee[0], committee[1]),
+		"repeated vote": newFinalisation(committee[0], committee[1], committee[1]),
+		"shard vote":    shardVote,
+		"forged vote":   forged,
+	} {
+		if err := keyring.VerifyFinalisation(finalisation); err == nil {
+			t.Errorf("expected finalisation with %s rejected", name)
+		}
+	}
+}
+
+// Validators lagging behind the beacon vote for different finalisations or cannot vote, but every accepted
+// finalisation extends the finalised paths.
+func TestBeaconCommittee(t *testing.T) {
+
+	for _, lag := range []BoundedRange{{0, 0}, {0, 1}} {
+
+		config := DefaultConfig()
+		config.Seed = 1
+		config.DebugShard = 0
+		config.BeaconCommitteeSize = 7
+		config.BeaconViewLag = lag
+		name := fmt.Sprintf("lag %s", lag.String())
+
+		eventClock := clock.NewEventClock()
+		simulation := Simulation{}
+		simulation.Init(&config, eventClock)
+		simulation.Start()
+		eventClock.Advance(5 * time.Minute)
+		simulation.Stop()
+
+		stats := simulation.Beacon().CommitteeStats()
+		if stats.Finalised == 0 || stats.Finalised != simulation.Beacon().Finalisation().Height {
+			t.Fatalf("%s: expected finalisation height %d, got %d", name, stats.Finalised, simulation.Beacon().Finalisation().Height)
+		}
+		if err := simulation.keys.VerifyFinalisation(simulation.Beacon().Finalisation()); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+
+		// Without lag every validator computes the beacon finalisation
+		diverged := stats.Split + stats.Behind
+		if lag.Max == 0 && diverged != 0 {
+			t.Errorf("%s: expected equal views, got %+v", name, stats)
+		}
+		if lag.Max > 0 && diverged == 0 {
+			t.Errorf("%s: expected divergent views, got %+v", name, stats)
+		}
+
+		for shard := 1; shard <= config.ShardCount; shard++ {
+			checkFinalisedPath(t, fmt.Sprintf("%s beacon shard %d", name, shard), simulation.Beacon().Chain(shard), false)
+			checkFinalisedPath(t, fmt.Sprintf("%s shard %d", name, shard), simulation.Shard(shard).Chain(shard), true)
+		}
+	}
+}
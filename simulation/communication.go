@@ -0,0 +1,173 @@
+package simulation
+
+import (
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+// Command controlling the beacon and shards
+type Command int8
+
+const (
+	Exit  Command = 0
+	Pause Command = 1
+	Run   Command = 2
+)
+
+// Channels of beacon (index 0) and shards (index 1..shardCount), messages travel over the network
+type Communication struct {
+	blocks       []chan *chain.Block
+	finalisation []chan *chain.Finalisation
+	control      []chan *Command
+	queries      []chan func()
+	clock        clock.Clock
+	network      *network
+}
+
+// Establish communication channels for beacon and shards
+func (communication *Communication) init(config *Config, clock clock.Clock) {
+
+	shardCount := config.ShardCount
+
+	communication.clock = clock
+	communication.blocks = make([]chan *chain.Block, shardCount+1)
+	communication.finalisation = make([]chan *chain.Finalisation, shardCount+1)
+	communication.control = make([]chan *Command, shardCount+1)
+	communication.queries = make([]chan func(), shardCount+1)
+
+	for i := 0; i <= shardCount; i++ {
+		communication.blocks[i] = make(chan *chain.Block, 100)
+		communication.finalisation[i] = make(chan *chain.Finalisation, 100)
+		communication.control[i] = make(chan *Command, 10)
+		communication.queries[i] = make(chan func())
+	}
+
+	communication.network = &network{}
+	communication.network.init(&config.Network, shardCount)
+}
+
+// Broadcast block of the source shard to the beacon and all shards
+func (communication *Communication) broadcastBlock(source int, block chain.Block) {
+	for actor := range communication.blocks {
+		communication.transmit(&message{from: source, to: actor, block: &block})
+	}
+}
+
+// Send block of the source shard to some actors only
+func (communication *Communication) multicastBlock(source int, actors []int, block chain.Block) {
+	for _, actor := range actors {
+		communication.transmit(&message{from: source, to: actor, block: &block})
+	}
+}
+
+// Broadcast finalisation to all shard and beacon shard
+func (communication *Communication) broadcastFinalisation(finalisation *chain.Finalisation) {
+	for actor := range communication.finalisation {
+		communication.transmit(&message{from: 0, to: actor, finalisation: finalisation})
+	}
+}
+
+// Broadcast command of the controller to all shard and beacon shard
+func (communication *Communication) broadCastCommand(command Command) {
+	for actor := range communication.control {
+		communication.transmit(&message{from: Controller, to: actor, command: &command})
+	}
+}
+
+// Deliver command to all actors at once, bypassing the network
+func (communication *Communication) signal(command Command) {
+	for actor := range communication.control {
+		communication.send(&message{from: Controller, to: actor, command: &command})
+	}
+}
+
+// Run query on the goroutine of the actor and wait until it returns, the actor must be running.
+// Queries bypass the network and the clock, they only read the state of the actor.
+func (communication *Communication) query(actor int, query func()) {
+	done := make(chan struct{})
+	communication.queries[actor] <- func() {
+		query()
+		close(done)
+	}
+	<-done
+}
+
+// Send message over the network, delayed messages are delivered by the clock
+func (communication *Communication) transmit(message *message) {
+
+	network := communication.network
+	if !network.delayed(message) {
+		communication.send(message)
+		return
+	}
+
+	now := communication.clock.Now()
+	if network.schedule(message, now) {
+		communication.clock.AfterFunc(message.at-now, func() {
+			communication.deliver(message)
+		})
+	}
+}
+
+// Move message from the network into the channel of the receiver
+func (communication *Communication) deliver(message *message) {
+	communication.network.delivery.Lock()
+	defer communication.network.delivery.Unlock()
+
+	communication.network.remove(message)
+	communication.send(message)
+}
+
+// Queue message in the channel of the receiver
+func (communication *Communication) send(message *message) {
+	communication.clock.Begin()
+	switch {
+	case message.block != nil:
+		communication.blocks[message.to] <- message.block
+	case message.finalisation != nil:
+		communication.finalisation[message.to] <- message.finalisation
+	default:
+		communication.control[message.to] <- message.command
+	}
+}
+
+// Take undelivered messages of actor, they stay queued in the channels.
+func (communication *Communication) pending(actor int) ([]*chain.Block, []*chain.Finalisation) {
+
+	blocks := make([]*chain.Block, len(communication.blocks[actor]))
+	for i := range blocks {
+		blocks[i] = <-communication.blocks[actor]
+	}
+	for _, block := range blocks {
+		communication.blocks[actor] <- block
+	}
+
+	finalisations := make([]*chain.Finalisation, len(communication.finalisation[actor]))
+	for i := range finalisations {
+		finalisations[i] = <-communication.finalisation[actor]
+	}
+	for _, finalisation := range finalisations {
+		communication.finalisation[actor] <- finalisation
+	}
+
+	return blocks, finalisations
+}
+
+// Queue block to a single actor
+func (communication *Communication) sendBlock(actor int, block *chain.Block) {
+	communication.send(&message{to: actor, block: block})
+}
+
+// Queue finalisation to a single actor
+func (communication *Communication) sendFinalisation(actor int, finalisation *chain.Finalisation) {
+	communication.send(&message{to: actor, finalisation: finalisation})
+}
+
+// Schedule a message that was in flight when the snapshot was taken
+func (communication *Communication) resend(message *message) {
+	now := communication.clock.Now()
+	communication.network.restore(message)
+	communication.clock.AfterFunc(message.at-now, func() {
+		communication.deliver(message)
+	})
+}
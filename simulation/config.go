@@ -0,0 +1,348 @@
+package simulation
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Config holds all parameters of a simulation.
+// Periods in seconds, probability as float.
+type Config struct {
+	ShardCount                     int             `json:"shardCount"`
+	Seed                           int64           `json:"seed"`
+	FinalisationPeriod             BoundedRange    `json:"finalisationPeriod"`
+	FinalisationProbability        float64         `json:"finalisationProbability"`
+	BeaconCommitteeSize            int             `json:"beaconCommitteeSize"`
+	BeaconViewLag                  BoundedRange    `json:"beaconViewLag"`
+	BlockGenerationPeriod          BoundedRange    `json:"blockGenerationPeriod"`
+	BlockGenerationProbability     float64         `json:"blockGenerationProbability"`
+	BlockTxInNumber                BoundedRange    `json:"blockTxInNumber"`
+	BlockTxOutNumber               BoundedRange    `json:"blockTxOutNumber"`
+	TXPoolSize                     int             `json:"txPoolSize"`
+	TXGenerationPeriod             BoundedRange    `json:"txGenerationPeriod"`
+	TXGenerationNumber             BoundedRange    `json:"txGenerationNumber"`
+	ProbabilityBuildOnLongestChain float64         `json:"probabilityBuildOnLongestChain"`
+	ForkChoice                     ForkChoices     `json:"forkChoice"`
+	Byzantine                      ByzantineShards `json:"byzantine,omitempty"`
+	Censoring                      CensoringShards `json:"censoring,omitempty"`
+	DebugShard                     int             `json:"debugShard"`
+	Verbose                        bool            `json:"verbose"`
+	CommitteeSize                  int             `json:"committeeSize"`
+	InitialStake                   int64           `json:"initialStake"`
+	StakePenalty                   int64           `json:"stakePenalty"`
+	OrphanTimeout                  int             `json:"orphanTimeout"`
+	Pruning                        bool            `json:"pruning"`
+	Network                        NetworkConfig   `json:"network"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ShardCount:                     4,
+		Seed:                           time.Now().UnixNano(),
+		FinalisationPeriod:             BoundedRange{3, 5},
+		FinalisationProbability:        .8,
+		BeaconCommitteeSize:            4,
+		BeaconViewLag:                  BoundedRange{0, 0},
+		BlockGenerationPeriod:          BoundedRange{1, 3},
+		BlockGenerationProbability:     1,
+		BlockTxInNumber:                BoundedRange{1, 4},
+		BlockTxOutNumber:               BoundedRange{1, 4},
+		TXPoolSize:                     20,
+		TXGenerationPeriod:             BoundedRange{1, 2},
+		TXGenerationNumber:             BoundedRange{1, 3},
+		ProbabilityBuildOnLongestChain: 0.90,
+		ForkChoice:                     ForkChoices{chain.LongestChain},
+		DebugShard:                     1,
+		CommitteeSize:                  4,
+		InitialStake:                   32000,
+		StakePenalty:                   1,
+		OrphanTimeout:                  60,
+		Network:                        NetworkConfig{Default: Link{Distribution: Uniform}},
+	}
+}
+
+// Register command line flags overriding the config.
+func (config *Config) RegisterFlags(flags *flag.FlagSet) {
+	flags.IntVar(&config.ShardCount, "shards", config.ShardCount, "number of shards")
+	flags.Int64Var(&config.Seed, "seed", config.Seed, "seed of the random sources, reuse to reproduce a run")
+	flags.Var(&config.FinalisationPeriod, "finalisation-period", "beacon finalisation period in seconds (min,max)")
+	flags.Float64Var(&config.FinalisationProbability, "finalisation-probability", config.FinalisationProbability, "probability a beacon validator votes in a finalisation round")
+	flags.IntVar(&config.BeaconCommitteeSize, "beacon-committee-size", config.BeaconCommitteeSize, "number of beacon validators voting on finalisations")
+	flags.Var(&config.BeaconViewLag, "beacon-view-lag", "seconds until a block arriving at the beacon is seen by a beacon validator (min,max)")
+	flags.Var(&config.BlockGenerationPeriod, "block-period", "block generation period in seconds (min,max)")
+	flags.Float64Var(&config.BlockGenerationProbability, "block-probability", config.BlockGenerationProbability, "probability a block is generated")
+	flags.Var(&config.BlockTxInNumber, "block-txin", "number of incoming transactions per block (min,max)")
+	flags.Var(&config.BlockTxOutNumber, "block-txout", "number of outgoing transactions per block (min,max)")
+	flags.IntVar(&config.TXPoolSize, "txpool-size", config.TXPoolSize, "maximum size of the outgoing transaction pool")
+	flags.Var(&config.TXGenerationPeriod, "tx-period", "transaction generation period in seconds (min,max)")
+	flags.Var(&config.TXGenerationNumber, "tx-number", "number of transactions generated per period (min,max)")
+	flags.Float64Var(&config.ProbabilityBuildOnLongestChain, "longest-chain-probability", config.ProbabilityBuildOnLongestChain, "probability a block builds on the best chain of the fork-choice rule")
+	flags.Var(&config.ForkChoice, "fork-choice", "fork-choice rule of all shards, or of every shard separated by commas: "+strings.Join(chain.ForkChoiceRules, ", "))
+	flags.Var(&config.Byzantine, "byzantine", "byzantine shard misbehaving in a fraction of its blocks (shard:behaviours:probability, e.g. 2:phantom-txin,withhold:0.5), repeatable; behaviours: "+strings.Join(Behaviours, ", "))
+	flags.Var(&config.Censoring, "censor", "shard ignoring incoming transactions of sources and types (shard:sources:types, e.g. 2:1,3:finalised or 2:all:all), repeatable; types: "+strings.Join(TXTypes, ", "))
+	flags.IntVar(&config.DebugShard, "debug-shard", config.DebugShard, "shard printing debug output, 0 for none")
+	flags.BoolVar(&config.Verbose, "verbose", config.Verbose, "print debug output of the beacon, e.g. every block and finalisation round")
+	flags.IntVar(&config.CommitteeSize, "committee-size", config.CommitteeSize, "number of validators per shard committee")
+	flags.Int64Var(&config.InitialStake, "stake", config.InitialStake, "initial stake of every validator")
+	flags.Int64Var(&config.StakePenalty, "stake-penalty", config.StakePenalty, "stake each committee validator loses per unprocessed cross-shard transaction per finalisation")
+	flags.IntVar(&config.OrphanTimeout, "orphan-timeout", config.OrphanTimeout, "seconds a block waits for its parent before it is discarded")
+	flags.BoolVar(&config.Pruning, "prune", config.Pruning, "prune finalised history of the block trees of other shards")
+	flags.IntVar(&config.Network.Default.Latency, "latency", config.Network.Default.Latency, "network latency between actors in milliseconds")
+	flags.IntVar(&config.Network.Default.Jitter, "jitter", config.Network.Default.Jitter, "network jitter in milliseconds, added to the latency")
+	flags.StringVar(&config.Network.Default.Distribution, "latency-distribution", config.Network.Default.Distribution, "distribution of the jitter: uniform, normal or exponential")
+	flags.Float64Var(&config.Network.Default.DropProbability, "drop-probability", config.Network.Default.DropProbability, "probability a block or finalisation is lost")
+	flags.Int64Var(&config.Network.Default.Bandwidth, "bandwidth", config.Network.Default.Bandwidth, "bandwidth of every link in bytes per second, 0 for unlimited")
+	flags.Var(&config.Network.Partitions, "partition", "partition the actors at start for duration in seconds (start:duration:groups, e.g. 60:30:1,2/3,4), repeatable")
+}
+
+// Load scenario file (JSON), fields missing in the file keep their value.
+func (config *Config) Load(path string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+	return nil
+}
+
+// Load scenario file and apply the command line on top: flags registers the parameters of config and was parsed
+// from arguments before. Repeatable flags set on the command line replace the lists of the file instead of adding to them.
+func (config *Config) LoadScenario(path string, flags *flag.FlagSet, arguments []string) error {
+
+	if err := config.Load(path); err != nil {
+		return err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "partition":
+			config.Network.Partitions = nil
+		case "byzantine":
+			config.Byzantine = nil
+		case "censor":
+			config.Censoring = nil
+		}
+	})
+	return flags.Parse(arguments)
+}
+
+// Validate config, return error describing the first invalid parameter.
+func (config *Config) Validate() error {
+
+	// Transactions need a different target shard
+	if config.ShardCount < 2 {
+		return fmt.Errorf("config: shardCount %d: at least 2 shards are required", config.ShardCount)
+	}
+
+	ranges := []struct {
+		name  string
+		value BoundedRange
+	}{
+		{"finalisationPeriod", config.FinalisationPeriod},
+		{"beaconViewLag", config.BeaconViewLag},
+		{"blockGenerationPeriod", config.BlockGenerationPeriod},
+		{"blockTxInNumber", config.BlockTxInNumber},
+		{"blockTxOutNumber", config.BlockTxOutNumber},
+		{"txGenerationPeriod", config.TXGenerationPeriod},
+		{"txGenerationNumber", config.TXGenerationNumber},
+	}
+	for _, r := range ranges {
+		if r.value.Min < 0 {
+			return fmt.Errorf("config: %s %v: min must not be negative", r.name, r.value.String())
+		}
+		if r.value.Min > r.value.Max {
+			return fmt.Errorf("config: %s %v: min must not exceed max", r.name, r.value.String())
+		}
+	}
+
+	// Timers of a period which is always zero fire without advancing the clock
+	periods := []struct {
+		name  string
+		value BoundedRange
+	}{
+		{"finalisationPeriod", config.FinalisationPeriod},
+		{"blockGenerationPeriod", config.BlockGenerationPeriod},
+		{"txGenerationPeriod", config.TXGenerationPeriod},
+	}
+	for _, period := range periods {
+		if period.value.Max < 1 {
+			return fmt.Errorf("config: %s %v: max must be at least 1 second", period.name, period.value.String())
+		}
+	}
+
+	probabilities := []struct {
+		name  string
+		value float64
+	}{
+		{"finalisationProbability", config.FinalisationProbability},
+		{"blockGenerationProbability", config.BlockGenerationProbability},
+		{"probabilityBuildOnLongestChain", config.ProbabilityBuildOnLongestChain},
+	}
+	for _, p := range probabilities {
+		if p.value < 0 || p.value > 1 {
+			return fmt.Errorf("config: %s %v: must be between 0 and 1", p.name, p.value)
+		}
+	}
+
+	if config.TXPoolSize < 0 {
+		return fmt.Errorf("config: txPoolSize %d: must not be negative", config.TXPoolSize)
+	}
+	if config.DebugShard < 0 || config.DebugShard > config.ShardCount {
+		return fmt.Errorf("config: debugShard %d: must be a shard between 1 and %d, or 0 for none", config.DebugShard, config.ShardCount)
+	}
+	if config.BeaconCommitteeSize < 1 {
+		return fmt.Errorf("config: beaconCommitteeSize %d: at least 1 beacon validator is required", config.BeaconCommitteeSize)
+	}
+	if config.CommitteeSize < 1 {
+		return fmt.Errorf("config: committeeSize %d: at least 1 validator per shard is required", config.CommitteeSize)
+	}
+	if config.InitialStake < 0 {
+		return fmt.Errorf("config: initialStake %d: must not be negative", config.InitialStake)
+	}
+	if config.StakePenalty < 0 {
+		return fmt.Errorf("config: stakePenalty %d: must not be negative", config.StakePenalty)
+	}
+	if config.OrphanTimeout < 1 {
+		return fmt.Errorf("config: orphanTimeout %d: must be at least 1 second", config.OrphanTimeout)
+	}
+	if len(config.ForkChoice) != 1 && len(config.ForkChoice) != config.ShardCount {
+		return fmt.Errorf("config: forkChoice %s: expected 1 rule or %d rules, one per shard", config.ForkChoice.String(), config.ShardCount)
+	}
+	for _, name := range config.ForkChoice {
+		if _, err := chain.NewForkChoice(name); err != nil {
+			return fmt.Errorf("config: forkChoice: %v", err)
+		}
+	}
+	if err := config.Byzantine.validate(config.ShardCount); err != nil {
+		return err
+	}
+	if err := config.Censoring.validate(config.ShardCount); err != nil {
+		return err
+	}
+
+	return config.Network.validate(config.ShardCount)
+}
+
+// Time a block waits for its parent
+func (config *Config) orphanTimeout() time.Duration {
+	return time.Duration(config.OrphanTimeout) * time.Second
+}
+
+// Fork-choice rule of shard, the config must be valid
+func (config *Config) forkChoice(shard int) chain.ForkChoice {
+	name := config.ForkChoice[0]
+	if len(config.ForkChoice) > 1 {
+		name = config.ForkChoice[shard-1]
+	}
+	forkChoice, _ := chain.NewForkChoice(name)
+	return forkChoice
+}
+
+// Range of destination shards of transactions
+func (config *Config) ShardRange() BoundedRange {
+	return BoundedRange{1, config.ShardCount}
+}
+
+// BoundedRange (min <= max) in seconds
+type BoundedRange struct {
+	Min int
+	Max int
+}
+
+// Generate random time period in range
+func (pr *BoundedRange) NextRandomTimePeriod(random *rand.Rand) time.Duration {
+	if pr.Min == pr.Max {
+		return time.Duration(pr.Min) * time.Second
+	}
+	return time.Duration(pr.Min*1000+random.Intn((pr.Max-pr.Min)*1000)) * time.Millisecond
+}
+
+// Generate random int in range
+func (pr *BoundedRange) NextRandomInt(random *rand.Rand) int {
+	return pr.Min + random.Intn(pr.Max+1 - pr.Min)
+}
+
+// Format range as "min,max", used by command line flags.
+func (pr *BoundedRange) String() string {
+	return fmt.Sprintf("%d,%d", pr.Min, pr.Max)
+}
+
+// Parse range from "min,max", or "value" for a fixed value.
+func (pr *BoundedRange) Set(value string) error {
+
+	parts := strings.Split(value, ",")
+	if len(parts) > 2 {
+		return fmt.Errorf("expected min,max")
+	}
+
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return fmt.Errorf("expected min,max: %v", err)
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return fmt.Errorf("expected min,max: %v", err)
+		}
+	}
+
+	pr.Min, pr.Max = min, max
+	return nil
+}
+
+// Encode range as [min, max]
+func (pr BoundedRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{pr.Min, pr.Max})
+}
+
+// Decode range from [min, max]
+func (pr *BoundedRange) UnmarshalJSON(data []byte) error {
+
+	var bounds [2]int
+	if err := json.Unmarshal(data, &bounds); err != nil {
+		return fmt.Errorf("expected [min, max]: %v", err)
+	}
+
+	pr.Min, pr.Max = bounds[0], bounds[1]
+	return nil
+}
+
+// Fork-choice rule of every shard (index shard-1), a single rule applies to all shards
+type ForkChoices []string
+
+// Format rules as "rule,rule", used by command line flags.
+func (rules *ForkChoices) String() string {
+	return strings.Join(*rules, ",")
+}
+
+// Parse rules from "rule" or "rule,rule"
+func (rules *ForkChoices) Set(value string) error {
+
+	parsed := ForkChoices{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if _, err := chain.NewForkChoice(name); err != nil {
+			return err
+		}
+		parsed = append(parsed, name)
+	}
+
+	*rules = parsed
+	return nil
+}
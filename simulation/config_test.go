@@ -0,0 +1,87 @@
+package simulation
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestValidatePeriods(t *testing.T) {
+
+	config := DefaultConfig()
+	config.BeaconViewLag = BoundedRange{0, 0}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("expected default config with zero view lag valid: %v", err)
+	}
+
+	for name, period := range map[string]*BoundedRange{
+		"finalisationPeriod":    &config.FinalisationPeriod,
+		"blockGenerationPeriod": &config.BlockGenerationPeriod,
+		"txGenerationPeriod":    &config.TXGenerationPeriod,
+	} {
+		valid := *period
+		for _, invalid := range []BoundedRange{{0, 0}, {3, 1}, {-1, 2}} {
+			*period = invalid
+			if err := config.Validate(); err == nil {
+				t.Errorf("expected error for %s %s", name, invalid.String())
+			}
+		}
+		*period = BoundedRange{0, 1}
+		if err := config.Validate(); err != nil {
+			t.Errorf("expected %s 0,1 valid: %v", name, err)
+		}
+		*period = valid
+	}
+}
+
+// Flags on the command line override the scenario file, repeatable flags replace its lists.
+func TestLoadScenario(t *testing.T) {
+
+	load := func(path string, arguments ...string) Config {
+		t.Helper()
+		config := DefaultConfig()
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		config.RegisterFlags(flags)
+		if err := flags.Parse(arguments); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.LoadScenario(path, flags, arguments); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("%s %v: %v", path, arguments, err)
+		}
+		return config
+	}
+
+	config := load("../scenarios/default.json", "-byzantine", "2:withhold:0.5", "-shards", "5")
+	if config.ShardCount != 5 || len(config.Byzantine) != 1 || config.Byzantine[0].Shard != 2 {
+		t.Errorf("expected 5 shards and byzantine shard 2, got %d shards and %s", config.ShardCount, config.Byzantine.String())
+	}
+
+	config = load("../scenarios/byzantine.json", "-byzantine", "3:withhold:0.5")
+	if len(config.Byzantine) != 1 || config.Byzantine[0].Shard != 3 {
+		t.Errorf("expected byzantine shard 3 replacing the file, got %s", config.Byzantine.String())
+	}
+	config = load("../scenarios/byzantine.json", "-seed", "2")
+	if len(config.Byzantine) != 1 || config.Byzantine[0].Shard != 2 || config.Seed != 2 {
+		t.Errorf("expected byzantine shard 2 of the file, got %s", config.Byzantine.String())
+	}
+
+	config = load("../scenarios/default.json", "-censor", "2:all:all", "-censor", "3:1:pending")
+	if len(config.Censoring) != 2 || config.Censoring[0].Shard != 2 || config.Censoring[1].Shard != 3 {
+		t.Errorf("expected censoring shards 2 and 3, got %s", config.Censoring.String())
+	}
+	config = load("../scenarios/censoring.json", "-censor", "4:all:all")
+	if len(config.Censoring) != 1 || config.Censoring[0].Shard != 4 {
+		t.Errorf("expected censoring shard 4 replacing the file, got %s", config.Censoring.String())
+	}
+	config = load("../scenarios/censoring.json", "-byzantine", "4:withhold:0.5")
+	if len(config.Censoring) != 2 || len(config.Byzantine) != 1 {
+		t.Errorf("expected censoring shards of the file and byzantine shard 4, got %s and %s", config.Censoring.String(), config.Byzantine.String())
+	}
+
+	config = load("../scenarios/partition.json", "-partition", "10:5:0")
+	if len(config.Network.Partitions) != 1 || config.Network.Default.Latency != 100 {
+		t.Errorf("expected the partition of the command line and the latency of the file, got %s", config.Network.Partitions.String())
+	}
+}
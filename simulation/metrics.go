@@ -0,0 +1,490 @@
+package simulation
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Metrics collects latencies of finalised cross-shard transactions per shard pair,
+// and how long finalised transactions wait for their target shard to process them.
+// Transactions are shared by all actors and never change, the times they reach a stage are kept here
+// by the actor observing it: shards record inclusion in their blocks, the beacon finalisation.
+type Metrics struct {
+	shardCount   int
+	latencies    map[shardPair]*pairLatencies
+	inconsistent map[shardPair]*pairInconsistency
+	unprocessed  []unprocessedTX
+	recorded     time.Duration
+
+	// Stages reached by transaction hash, until finalised in the target shard.
+	// Written by the shard goroutines and the beacon.
+	progress sync.Mutex
+	stages   map[string]*txStages
+}
+
+// Stage of a transaction not (yet) reached
+const notReached time.Duration = -1
+
+// Times a transaction reached the stages before its finalisation in the target shard. Included times
+// refer to the first block including the transaction, which can be a stale block.
+type txStages struct {
+	sourceIncluded  time.Duration
+	targetIncluded  time.Duration
+	sourceFinalised time.Duration
+}
+
+type shardPair struct {
+	source int
+	target int
+}
+
+type pairLatencies struct {
+	// Creation until first included in a block of the source shard, and until that shard finalised it
+	sourceIncluded  []time.Duration
+	sourceFinalised []time.Duration
+	// Creation until first included in a block of the target shard
+	processed []time.Duration
+	// Creation until the block of the target shard is finalised
+	finalised []time.Duration
+}
+
+type pairInconsistency struct {
+	// First listed as inconsistent by a finalisation until processed, of processed transactions
+	processed []time.Duration
+	// Stake penalty of the target committee, every validator pays it
+	penalty int64
+}
+
+// Transaction listed as inconsistent by the last finalisation, since it was first listed
+type unprocessedTX struct {
+	tx    *chain.Transaction
+	since time.Duration
+}
+
+// Time finalised transactions of a shard pair wait to be processed, and the penalty it costs
+type InconsistencyStatistics struct {
+	Processed   LatencyStatistics
+	Unprocessed int
+	// Longest wait of an unprocessed transaction, until the last finalisation
+	LongestUnprocessed time.Duration
+	Penalty            int64
+}
+
+// Distribution of latencies
+type LatencyStatistics struct {
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+func (metrics *Metrics) init(shardCount int) {
+	metrics.shardCount = shardCount
+	metrics.latencies = make(map[shardPair]*pairLatencies)
+	metrics.inconsistent = make(map[shardPair]*pairInconsistency)
+	metrics.unprocessed = make([]unprocessedTX, 0)
+	metrics.stages = make(map[string]*txStages)
+}
+
+// Stages of transaction, call with the progress lock held
+func (metrics *Metrics) txStages(tx *chain.Transaction) *txStages {
+	stages, ok := metrics.stages[tx.Hash]
+	if !ok {
+		stages = &txStages{sourceIncluded: notReached, targetIncluded: notReached, sourceFinalised: notReached}
+		metrics.stages[tx.Hash] = stages
+	}
+	return stages
+}
+
+// Record the transactions of a block produced at time now: outgoing transactions are included in
+// the source shard, incoming ones in the target shard.
+func (metrics *Metrics) RecordIncluded(block *chain.Block, now time.Duration) {
+
+	metrics.progress.Lock()
+	defer metrics.progress.Unlock()
+
+	for _, tx := range block.TXOut {
+		if stages := metrics.txStages(tx); stages.sourceIncluded == notReached {
+			stages.sourceIncluded = now
+		}
+	}
+	for _, tx := range block.TXIn {
+		if stages := metrics.txStages(tx); stages.targetIncluded == notReached {
+			stages.targetIncluded = now
+		}
+	}
+}
+
+// Record the outgoing transactions of a block which is finalised in the source shard at time now. Transactions
+// already finalised in the target shard, e.g. inconsistent ones, are not tracked anymore.
+func (metrics *Metrics) RecordSourceFinalised(block *chain.Block, now time.Duration) {
+
+	metrics.progress.Lock()
+	defer metrics.progress.Unlock()
+
+	for _, tx := range block.TXOut {
+		if stages, ok := metrics.stages[tx.Hash]; ok && stages.sourceFinalised == notReached {
+			stages.sourceFinalised = now
+		}
+	}
+}
+
+// Record latencies of a transaction which is finalised in the target shard at time finalised.
+func (metrics *Metrics) Record(tx *chain.Transaction, finalised time.Duration) {
+
+	pair := shardPair{source: tx.SourceShard, target: tx.TargetShard}
+
+	latencies, ok := metrics.latencies[pair]
+	if !ok {
+		latencies = &pairLatencies{}
+		metrics.latencies[pair] = latencies
+	}
+
+	metrics.progress.Lock()
+	stages := *metrics.txStages(tx)
+	delete(metrics.stages, tx.Hash)
+	metrics.progress.Unlock()
+
+	if stages.sourceIncluded != notReached {
+		latencies.sourceIncluded = append(latencies.sourceIncluded, stages.sourceIncluded-tx.Created)
+	}
+	if stages.sourceFinalised != notReached {
+		latencies.sourceFinalised = append(latencies.sourceFinalised, stages.sourceFinalised-tx.Created)
+	}
+	if stages.targetIncluded != notReached {
+		latencies.processed = append(latencies.processed, stages.targetIncluded-tx.Created)
+	}
+	latencies.finalised = append(latencies.finalised, finalised-tx.Created)
+}
+
+// Record the inconsistent transactions of a finalisation at time now, every listing costs the target
+// committee penalty. Transactions listed by the previous finalisation but not by this one are processed.
+func (metrics *Metrics) RecordInconsistent(finalisation *chain.Finalisation, now time.Duration, penalty int64) {
+
+	previous := make(map[string]time.Duration, len(metrics.unprocessed))
+	for _, waiting := range metrics.unprocessed {
+		previous[waiting.tx.Hash] = waiting.since
+	}
+
+	listed := make(map[string]bool, len(finalisation.InconsistentTX))
+	unprocessed := make([]unprocessedTX, 0, len(finalisation.InconsistentTX))
+	for _, tx := range finalisation.InconsistentTX {
+		metrics.pairInconsistency(tx).penalty += penalty
+
+		if listed[tx.Hash] {
+			continue
+		}
+		listed[tx.Hash] = true
+
+		since, ok := previous[tx.Hash]
+		if !ok {
+			since = now
+		}
+		unprocessed = append(unprocessed, unprocessedTX{tx: tx, since: since})
+	}
+
+	for _, waiting := range metrics.unprocessed {
+		if !listed[waiting.tx.Hash] {
+			pair := metrics.pairInconsistency(waiting.tx)
+			pair.processed = append(pair.processed, now-waiting.since)
+		}
+	}
+
+	metrics.unprocessed = unprocessed
+	metrics.recorded = now
+}
+
+func (metrics *Metrics) pairInconsistency(tx *chain.Transaction) *pairInconsistency {
+
+	pair := shardPair{source: tx.SourceShard, target: tx.TargetShard}
+
+	inconsistency, ok := metrics.inconsistent[pair]
+	if !ok {
+		inconsistency = &pairInconsistency{}
+		metrics.inconsistent[pair] = inconsistency
+	}
+	return inconsistency
+}
+
+// Time finalised transactions wait in the inconsistent transactions until processed in the target shard.
+func (metrics *Metrics) Inconsistency(source int, target int) InconsistencyStatistics {
+
+	statistics := InconsistencyStatistics{}
+
+	if inconsistency, ok := metrics.inconsistent[shardPair{source: source, target: target}]; ok {
+		statistics.Processed = latencyStatistics(inconsistency.processed)
+		statistics.Penalty = inconsistency.penalty
+	}
+
+	for _, waiting := range metrics.unprocessed {
+		if waiting.tx.SourceShard == source && waiting.tx.TargetShard == target {
+			statistics.Unprocessed++
+			if wait := metrics.recorded - waiting.since; wait > statistics.LongestUnprocessed {
+				statistics.LongestUnprocessed = wait
+			}
+		}
+	}
+
+	return statistics
+}
+
+// Latency statistics from creation until processed and finalised in the target shard.
+func (metrics *Metrics) Statistics(source int, target int) (LatencyStatistics, LatencyStatistics) {
+
+	latencies, ok := metrics.latencies[shardPair{source: source, target: target}]
+	if !ok {
+		return LatencyStatistics{}, LatencyStatistics{}
+	}
+
+	return latencyStatistics(latencies.processed), latencyStatistics(latencies.finalised)
+}
+
+// Latency statistics from creation until included and finalised in the source shard, of transactions
+// finalised in the target shard.
+func (metrics *Metrics) SourceStatistics(source int, target int) (LatencyStatistics, LatencyStatistics) {
+
+	latencies, ok := metrics.latencies[shardPair{source: source, target: target}]
+	if !ok {
+		return LatencyStatistics{}, LatencyStatistics{}
+	}
+
+	return latencyStatistics(latencies.sourceIncluded), latencyStatistics(latencies.sourceFinalised)
+}
+
+// Print latency statistics of every shard pair with finalised transactions.
+func (metrics *Metrics) PrintReport() {
+
+	fmt.Println("Transaction latency in seconds (mean / p50 / p95 / p99)")
+
+	for source := 1; source <= metrics.shardCount; source++ {
+		for target := 1; target <= metrics.shardCount; target++ {
+
+			processed, finalised := metrics.Statistics(source, target)
+			if finalised.Count == 0 {
+				continue
+			}
+			sourceIncluded, sourceFinalised := metrics.SourceStatistics(source, target)
+
+			fmt.Printf("[shard %d -> %d] TX: %d - source included: %s - source finalised: %s - processed: %s - finalised: %s\n",
+				source, target, finalised.Count, sourceIncluded.String(), sourceFinalised.String(), processed.String(), finalised.String())
+		}
+	}
+}
+
+// Print time in the inconsistent transactions and penalty of every shard pair with inconsistent transactions.
+func (metrics *Metrics) PrintInconsistencyReport() {
+
+	fmt.Println("Inconsistent transactions: seconds from first listed by a finalisation until processed (mean / p50 / p95 / p99), stake penalty per validator")
+
+	for source := 1; source <= metrics.shardCount; source++ {
+		for target := 1; target <= metrics.shardCount; target++ {
+
+			statistics := metrics.Inconsistency(source, target)
+			if statistics.Processed.Count == 0 && statistics.Unprocessed == 0 {
+				continue
+			}
+
+			fmt.Printf("[shard %d -> %d] processed TX: %d - inconsistent: %s - unprocessed TX: %d - longest unprocessed: %.2f - penalty: %d\n",
+				source, target, statistics.Processed.Count, statistics.Processed.String(), statistics.Unprocessed,
+				statistics.LongestUnprocessed.Seconds(), statistics.Penalty)
+		}
+	}
+}
+
+func (statistics LatencyStatistics) String() string {
+	return fmt.Sprintf("%.2f / %.2f / %.2f / %.2f",
+		statistics.Mean.Seconds(), statistics.P50.Seconds(), statistics.P95.Seconds(), statistics.P99.Seconds())
+}
+
+func latencyStatistics(latencies []time.Duration) LatencyStatistics {
+
+	if len(latencies) == 0 {
+		return LatencyStatistics{}
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	total := time.Duration(0)
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	return LatencyStatistics{
+		Count: len(sorted),
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(sorted, 50),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+	}
+}
+
+// Nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Serialisable latencies of a shard pair
+type LatencySnapshot struct {
+	Source          int             `json:"source"`
+	Target          int             `json:"target"`
+	SourceIncluded  []time.Duration `json:"sourceIncluded"`
+	SourceFinalised []time.Duration `json:"sourceFinalised"`
+	Processed       []time.Duration `json:"processed"`
+	Finalised       []time.Duration `json:"finalised"`
+}
+
+func (metrics *Metrics) snapshot() []LatencySnapshot {
+
+	snapshot := make([]LatencySnapshot, 0, len(metrics.latencies))
+
+	for source := 1; source <= metrics.shardCount; source++ {
+		for target := 1; target <= metrics.shardCount; target++ {
+			if latencies, ok := metrics.latencies[shardPair{source: source, target: target}]; ok {
+				snapshot = append(snapshot, LatencySnapshot{
+					Source:          source,
+					Target:          target,
+					SourceIncluded:  latencies.sourceIncluded,
+					SourceFinalised: latencies.sourceFinalised,
+					Processed:       latencies.processed,
+					Finalised:       latencies.finalised,
+				})
+			}
+		}
+	}
+
+	return snapshot
+}
+
+// Stages reached by a transaction not finalised in the target shard yet, by hex encoded hash
+type StagesSnapshot struct {
+	TX              string        `json:"tx"`
+	SourceIncluded  time.Duration `json:"sourceIncluded"`
+	TargetIncluded  time.Duration `json:"targetIncluded"`
+	SourceFinalised time.Duration `json:"sourceFinalised"`
+}
+
+func (metrics *Metrics) stagesSnapshot() []StagesSnapshot {
+
+	metrics.progress.Lock()
+	defer metrics.progress.Unlock()
+
+	snapshot := make([]StagesSnapshot, 0, len(metrics.stages))
+	for hash, stages := range metrics.stages {
+		snapshot = append(snapshot, StagesSnapshot{
+			TX:              hex.EncodeToString([]byte(hash)),
+			SourceIncluded:  stages.sourceIncluded,
+			TargetIncluded:  stages.targetIncluded,
+			SourceFinalised: stages.sourceFinalised,
+		})
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].TX < snapshot[j].TX })
+
+	return snapshot
+}
+
+func (metrics *Metrics) restoreStages(snapshot []StagesSnapshot) error {
+	for _, stages := range snapshot {
+		hash, err := hex.DecodeString(stages.TX)
+		if err != nil {
+			return fmt.Errorf("transaction stages: %v", err)
+		}
+		metrics.stages[string(hash)] = &txStages{
+			sourceIncluded:  stages.SourceIncluded,
+			targetIncluded:  stages.TargetIncluded,
+			sourceFinalised: stages.SourceFinalised,
+		}
+	}
+	return nil
+}
+
+func (metrics *Metrics) restore(snapshot []LatencySnapshot) {
+	for _, pair := range snapshot {
+		metrics.latencies[shardPair{source: pair.Source, target: pair.Target}] = &pairLatencies{
+			sourceIncluded:  pair.SourceIncluded,
+			sourceFinalised: pair.SourceFinalised,
+			processed:       pair.Processed,
+			finalised:       pair.Finalised,
+		}
+	}
+}
+
+// Serialisable inconsistency of every shard pair and the unprocessed transactions
+type InconsistencySnapshot struct {
+	Pairs       []PairInconsistencySnapshot `json:"pairs"`
+	Unprocessed []UnprocessedSnapshot       `json:"unprocessed"`
+	Recorded    time.Duration               `json:"recorded"`
+}
+
+type PairInconsistencySnapshot struct {
+	Source    int             `json:"source"`
+	Target    int             `json:"target"`
+	Processed []time.Duration `json:"processed"`
+	Penalty   int64           `json:"penalty"`
+}
+
+// Unprocessed transaction by reference, listed as inconsistent since
+type UnprocessedSnapshot struct {
+	TX    string        `json:"tx"`
+	Since time.Duration `json:"since"`
+}
+
+func (metrics *Metrics) inconsistencySnapshot(table chain.TransactionTable) InconsistencySnapshot {
+
+	snapshot := InconsistencySnapshot{
+		Pairs:       make([]PairInconsistencySnapshot, 0, len(metrics.inconsistent)),
+		Unprocessed: make([]UnprocessedSnapshot, len(metrics.unprocessed)),
+		Recorded:    metrics.recorded,
+	}
+
+	for source := 1; source <= metrics.shardCount; source++ {
+		for target := 1; target <= metrics.shardCount; target++ {
+			if inconsistency, ok := metrics.inconsistent[shardPair{source: source, target: target}]; ok {
+				snapshot.Pairs = append(snapshot.Pairs, PairInconsistencySnapshot{
+					Source:    source,
+					Target:    target,
+					Processed: inconsistency.processed,
+					Penalty:   inconsistency.penalty,
+				})
+			}
+		}
+	}
+
+	for i, waiting := range metrics.unprocessed {
+		snapshot.Unprocessed[i] = UnprocessedSnapshot{TX: table.Add(waiting.tx), Since: waiting.since}
+	}
+
+	return snapshot
+}
+
+func (metrics *Metrics) restoreInconsistency(snapshot InconsistencySnapshot, table chain.TransactionTable) error {
+
+	for _, pair := range snapshot.Pairs {
+		metrics.inconsistent[shardPair{source: pair.Source, target: pair.Target}] = &pairInconsistency{
+			processed: pair.Processed,
+			penalty:   pair.Penalty,
+		}
+	}
+
+	for _, waiting := range snapshot.Unprocessed {
+		transactions, err := table.Resolve([]string{waiting.TX})
+		if err != nil {
+			return fmt.Errorf("unprocessed transaction: %v", err)
+		}
+		metrics.unprocessed = append(metrics.unprocessed, unprocessedTX{tx: transactions[0], since: waiting.Since})
+	}
+	metrics.recorded = snapshot.Recorded
+
+	return nil
+}
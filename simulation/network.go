@@ -0,0 +1,509 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Actor sending the commands of the front-end, used as source of a link
+const Controller = -1
+
+// Latency distributions of a link
+const (
+	Uniform     = "uniform"
+	Normal      = "normal"
+	Exponential = "exponential"
+)
+
+// Approximate message sizes in bytes, used for the bandwidth of a link
+const (
+	blockHeaderSize        = 128
+	finalisationHeaderSize = 64
+	transactionSize        = 96
+	voteSize               = 96
+	commandSize            = 8
+)
+
+// Link between two actors, latency and jitter in milliseconds, bandwidth in bytes per second.
+// Uniform adds up to jitter, normal adds a deviation of jitter and exponential adds a mean of jitter.
+type Link struct {
+	Latency         int     `json:"latency"`
+	Jitter          int     `json:"jitter"`
+	Distribution    string  `json:"distribution"`
+	DropProbability float64 `json:"dropProbability"`
+	Bandwidth       int64   `json:"bandwidth"`
+}
+
+// Link overriding the default link from one actor to another
+type LinkConfig struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	Link
+}
+
+// Network between the beacon (0), the shards (1..shardCount) and the controller.
+type NetworkConfig struct {
+	Default    Link         `json:"default"`
+	Links      []LinkConfig `json:"links,omitempty"`
+	Partitions Partitions   `json:"partitions,omitempty"`
+}
+
+// Partition splitting the actors into groups from start for duration, in seconds.
+// Actors outside the groups form a group of their own, the controller is never partitioned.
+type Partition struct {
+	Start    int     `json:"start"`
+	Duration int     `json:"duration"`
+	Groups   [][]int `json:"groups"`
+}
+
+// Time the partition heals
+func (partition *Partition) end() time.Duration {
+	return time.Duration(partition.Start+partition.Duration) * time.Second
+}
+
+// Whether the partition is in effect at time now
+func (partition *Partition) active(now time.Duration) bool {
+	return now >= time.Duration(partition.Start)*time.Second && now < partition.end()
+}
+
+// Group of the actor, -1 for actors outside the groups
+func (partition *Partition) group(actor int) int {
+	for i, group := range partition.Groups {
+		for _, member := range group {
+			if member == actor {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Whether the partition separates two actors
+func (partition *Partition) separates(from int, to int) bool {
+	return from != Controller && partition.group(from) != partition.group(to)
+}
+
+// Format partition as "start:duration:groups", e.g. "60:30:1,2/3,4"
+func (partition *Partition) String() string {
+	groups := make([]string, len(partition.Groups))
+	for i, group := range partition.Groups {
+		members := make([]string, len(group))
+		for j, member := range group {
+			members[j] = strconv.Itoa(member)
+		}
+		groups[i] = strings.Join(members, ",")
+	}
+	return fmt.Sprintf("%d:%d:%s", partition.Start, partition.Duration, strings.Join(groups, "/"))
+}
+
+// Partitions of the network, in effect one after another or overlapping
+type Partitions []Partition
+
+func (partitions *Partitions) String() string {
+	list := make([]string, len(*partitions))
+	for i := range *partitions {
+		list[i] = (*partitions)[i].String()
+	}
+	return strings.Join(list, " ")
+}
+
+// Add partition from "start:duration:groups", used by the repeatable command line flag
+func (partitions *Partitions) Set(value string) error {
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected start:duration:groups")
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return fmt.Errorf("expected start:duration:groups: %v", err)
+	}
+	duration, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return fmt.Errorf("expected start:duration:groups: %v", err)
+	}
+
+	partition := Partition{Start: start, Duration: duration}
+	for _, group := range strings.Split(parts[2], "/") {
+		members := make([]int, 0)
+		for _, member := range strings.Split(group, ",") {
+			actor, err := strconv.Atoi(strings.TrimSpace(member))
+			if err != nil {
+				return fmt.Errorf("expected groups as 1,2/3,4: %v", err)
+			}
+			members = append(members, actor)
+		}
+		partition.Groups = append(partition.Groups, members)
+	}
+
+	*partitions = append(*partitions, partition)
+	return nil
+}
+
+// Link without delay, loss or bandwidth limit
+func (link *Link) ideal() bool {
+	return link.Latency == 0 && link.Jitter == 0 && link.DropProbability == 0 && link.Bandwidth == 0
+}
+
+// Random one-way latency of the link
+func (link *Link) sample(random *rand.Rand) time.Duration {
+
+	latency := float64(link.Latency)
+	jitter := float64(link.Jitter)
+
+	switch link.Distribution {
+	case Normal:
+		latency += random.NormFloat64() * jitter
+	case Exponential:
+		latency += random.ExpFloat64() * jitter
+	default:
+		latency += random.Float64() * jitter
+	}
+
+	if latency < 0 {
+		latency = 0
+	}
+	return time.Duration(latency * float64(time.Millisecond))
+}
+
+// Time needed to put size bytes on the link
+func (link *Link) transmission(size int) time.Duration {
+	if link.Bandwidth == 0 {
+		return 0
+	}
+	return time.Duration(int64(size) * int64(time.Second) / link.Bandwidth)
+}
+
+func (link *Link) validate(name string) error {
+	if link.Latency < 0 {
+		return fmt.Errorf("config: %s latency %d: must not be negative", name, link.Latency)
+	}
+	if link.Jitter < 0 {
+		return fmt.Errorf("config: %s jitter %d: must not be negative", name, link.Jitter)
+	}
+	switch link.Distribution {
+	case "", Uniform, Normal, Exponential:
+	default:
+		return fmt.Errorf("config: %s distribution %q: must be %s, %s or %s", name, link.Distribution, Uniform, Normal, Exponential)
+	}
+	if link.DropProbability < 0 || link.DropProbability > 1 {
+		return fmt.Errorf("config: %s dropProbability %v: must be between 0 and 1", name, link.DropProbability)
+	}
+	if link.Bandwidth < 0 {
+		return fmt.Errorf("config: %s bandwidth %d: must not be negative", name, link.Bandwidth)
+	}
+	return nil
+}
+
+// Validate links, actors are the controller, the beacon and shards 1..shardCount.
+func (config *NetworkConfig) validate(shardCount int) error {
+
+	if err := config.Default.validate("network default"); err != nil {
+		return err
+	}
+
+	for _, link := range config.Links {
+		name := fmt.Sprintf("network link %d-%d", link.From, link.To)
+		if link.From < Controller || link.From > shardCount {
+			return fmt.Errorf("config: %s: from must be an actor between %d and %d", name, Controller, shardCount)
+		}
+		if link.To < 0 || link.To > shardCount {
+			return fmt.Errorf("config: %s: to must be an actor between 0 and %d", name, shardCount)
+		}
+		if err := link.Link.validate(name); err != nil {
+			return err
+		}
+	}
+
+	for i := range config.Partitions {
+		partition := &config.Partitions[i]
+		name := fmt.Sprintf("network partition %s", partition.String())
+		if partition.Start < 0 {
+			return fmt.Errorf("config: %s: start must not be negative", name)
+		}
+		if partition.Duration < 1 {
+			return fmt.Errorf("config: %s: duration must be at least 1 second", name)
+		}
+		if len(partition.Groups) == 0 {
+			return fmt.Errorf("config: %s: at least 1 group is required", name)
+		}
+		seen := make(map[int]bool)
+		for _, group := range partition.Groups {
+			for _, actor := range group {
+				if actor < 0 || actor > shardCount {
+					return fmt.Errorf("config: %s: actor %d must be between 0 and %d", name, actor, shardCount)
+				}
+				if seen[actor] {
+					return fmt.Errorf("config: %s: actor %d in more than 1 group", name, actor)
+				}
+				seen[actor] = true
+			}
+		}
+	}
+	return nil
+}
+
+// Traffic sent by an actor, delay of the delivered messages including transmission and partitions
+type NetworkStats struct {
+	Messages int
+	Dropped  int
+	Held     int
+	Delay    time.Duration
+}
+
+// Mean delay of the delivered messages
+func (stats *NetworkStats) MeanDelay() time.Duration {
+	delivered := stats.Messages - stats.Dropped
+	if delivered == 0 {
+		return 0
+	}
+	return stats.Delay / time.Duration(delivered)
+}
+
+// Message on its way from one actor to another
+type message struct {
+	sequence     uint64
+	from         int
+	to           int
+	at           time.Duration
+	block        *chain.Block
+	finalisation *chain.Finalisation
+	command      *Command
+}
+
+// Message size in bytes
+func (message *message) size() int {
+	switch {
+	case message.block != nil:
+		return blockHeaderSize + transactionSize*(len(message.block.TXIn)+len(message.block.TXOut))
+	case message.finalisation != nil:
+		return finalisationHeaderSize + blockHeaderSize*len(message.finalisation.Blocks) +
+			transactionSize*len(message.finalisation.InconsistentTX) + voteSize*len(message.finalisation.Votes)
+	default:
+		return commandSize
+	}
+}
+
+// State of a link, only used by the sending actor
+type linkState struct {
+	busyUntil    time.Duration
+	lastDelivery time.Duration
+}
+
+// Network delays and drops messages between actors. Every sender has its own random
+// source and link state, so a run on the event clock stays reproducible. The controller
+// sends from the front-end goroutine.
+type network struct {
+	links      [][]Link
+	partitions Partitions
+	state      [][]linkState
+	random     []*rand.Rand
+	stats      []NetworkStats
+
+	// Messages in flight and traffic stats, delivery holds off snapshots while moving a message into a channel
+	mutex    sync.Mutex
+	delivery sync.Mutex
+	sequence uint64
+	inFlight map[uint64]*message
+}
+
+func (network *network) init(config *NetworkConfig, shardCount int) {
+
+	// Senders: beacon, shards and controller (last)
+	senders := shardCount + 2
+	network.links = make([][]Link, senders)
+	network.state = make([][]linkState, senders)
+	for i := range network.links {
+		network.links[i] = make([]Link, shardCount+1)
+		for j := range network.links[i] {
+			network.links[i][j] = config.Default
+		}
+		network.state[i] = make([]linkState, shardCount+1)
+	}
+	for _, link := range config.Links {
+		network.links[network.sender(link.From)][link.To] = link.Link
+	}
+	network.partitions = config.Partitions
+
+	network.stats = make([]NetworkStats, senders)
+	network.inFlight = make(map[uint64]*message)
+}
+
+// Random source of every sender derived from seeds
+func (network *network) seed(seeds *rand.Rand) {
+	network.random = make([]*rand.Rand, len(network.links))
+	for i := range network.random {
+		network.random[i] = rand.New(rand.NewSource(seeds.Int63()))
+	}
+}
+
+// Index of sending actor
+func (network *network) sender(actor int) int {
+	if actor == Controller {
+		return len(network.links) - 1
+	}
+	return actor
+}
+
+// Own messages and ideal links without partitions deliver directly
+func (network *network) delayed(message *message) bool {
+
+	if message.from == message.to {
+		return false
+	}
+	for i := range network.partitions {
+		if network.partitions[i].separates(message.from, message.to) {
+			return true
+		}
+	}
+	return !network.links[network.sender(message.from)][message.to].ideal()
+}
+
+// Time a message between two actors can leave, after the partitions separating them have healed
+func (network *network) heal(from int, to int, now time.Duration) time.Duration {
+
+	for healed := false; !healed; {
+		healed = true
+		for i := range network.partitions {
+			partition := &network.partitions[i]
+			if partition.active(now) && partition.separates(from, to) {
+				now = partition.end()
+				healed = false
+			}
+		}
+	}
+	return now
+}
+
+// Schedule delivery time of the message over its link, false if the message is lost.
+func (network *network) schedule(message *message, now time.Duration) bool {
+
+	sender := network.sender(message.from)
+	link := &network.links[sender][message.to]
+	random := network.random[sender]
+	state := &network.state[sender][message.to]
+	stats := NetworkStats{Messages: 1}
+	defer network.count(sender, &stats)
+
+	// Partitions hold messages back until they heal
+	start := network.heal(message.from, message.to, now)
+	if start > now {
+		stats.Held++
+	}
+
+	// Messages queue for the bandwidth of the link, lost ones included
+	if state.busyUntil > start {
+		start = state.busyUntil
+	}
+	state.busyUntil = start + link.transmission(message.size())
+
+	// Commands are never lost
+	if message.command == nil && random.Float64() < link.DropProbability {
+		stats.Dropped++
+		return false
+	}
+
+	// Links deliver in order
+	message.at = state.busyUntil + link.sample(random)
+	if message.at < state.lastDelivery {
+		message.at = state.lastDelivery
+	}
+	state.lastDelivery = message.at
+	stats.Delay += message.at - now
+
+	network.add(message)
+	return true
+}
+
+// Add traffic of sender
+func (network *network) count(sender int, stats *NetworkStats) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	total := &network.stats[sender]
+	total.Messages += stats.Messages
+	total.Dropped += stats.Dropped
+	total.Held += stats.Held
+	total.Delay += stats.Delay
+}
+
+// Copy of the traffic of sender so far
+func (network *network) traffic(sender int) NetworkStats {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	return network.stats[sender]
+}
+
+// Add message to the messages in flight
+func (network *network) add(message *message) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	network.sequence++
+	message.sequence = network.sequence
+	network.inFlight[message.sequence] = message
+}
+
+// Take message from the messages in flight
+func (network *network) remove(message *message) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	delete(network.inFlight, message.sequence)
+}
+
+// Messages in flight in delivery order, commands excluded
+func (network *network) messages() []*message {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	messages := make([]*message, 0, len(network.inFlight))
+	for _, message := range network.inFlight {
+		if message.command == nil {
+			messages = append(messages, message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].at == messages[j].at {
+			return messages[i].sequence < messages[j].sequence
+		}
+		return messages[i].at < messages[j].at
+	})
+	return messages
+}
+
+// Keep a restored message in order with later messages over its link
+func (network *network) restore(message *message) {
+	state := &network.state[network.sender(message.from)][message.to]
+	if message.at > state.lastDelivery {
+		state.lastDelivery = message.at
+	}
+	network.add(message)
+}
+
+// Print traffic of every sender with messages
+func (network *network) printReport() {
+	for i, stats := range network.stats {
+		if stats.Messages == 0 {
+			continue
+		}
+		name := fmt.Sprintf("shard %d", i)
+		switch {
+		case i == 0:
+			name = "beacon"
+		case i == len(network.stats)-1:
+			name = "controller"
+		}
+		fmt.Printf("[network] %s - messages: %d - dropped: %d - held by partitions: %d - mean delay: %v\n",
+			name, stats.Messages, stats.Dropped, stats.Held, stats.MeanDelay())
+	}
+}
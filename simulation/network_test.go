@@ -0,0 +1,239 @@
+package simulation
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func TestNetworkLink(t *testing.T) {
+
+	config := NetworkConfig{
+		Default: Link{Latency: 100, Jitter: 400, Distribution: Normal, Bandwidth: 1000},
+		Links: []LinkConfig{
+			{From: 1, To: 2, Link: Link{DropProbability: 1}},
+		},
+	}
+	if err := config.validate(2); err != nil {
+		t.Fatal(err)
+	}
+
+	network := network{}
+	network.init(&config, 2)
+	network.seed(rand.New(rand.NewSource(1)))
+
+	// Own and ideal links deliver directly
+	if network.delayed(&message{from: 1, to: 1}) {
+		t.Errorf("expected direct delivery to the sender itself")
+	}
+	if !network.delayed(&message{from: 1, to: 0}) {
+		t.Errorf("expected delayed delivery over the default link")
+	}
+
+	// Links deliver in order and queue for their bandwidth
+	previous := time.Duration(0)
+	for i := 0; i < 100; i++ {
+		block := &message{from: 1, to: 0, block: &chain.Block{}}
+		if !network.schedule(block, 0) {
+			t.Fatalf("message %d lost", i)
+		}
+		if block.at < previous {
+			t.Fatalf("message %d at %v delivered before message at %v", i, block.at, previous)
+		}
+		previous = block.at
+	}
+	if busy := network.state[1][0].busyUntil; busy != 100*blockHeaderSize*time.Second/1000 {
+		t.Errorf("expected link busy until %v, got %v", 100*blockHeaderSize*time.Second/1000, busy)
+	}
+
+	// Blocks are lost, commands are not
+	if network.schedule(&message{from: 1, to: 2, block: &chain.Block{}}, 0) {
+		t.Errorf("expected block to be lost")
+	}
+	command := Pause
+	if !network.schedule(&message{from: 1, to: 2, command: &command}, 0) {
+		t.Errorf("expected command to be delivered")
+	}
+
+	stats := network.stats[1]
+	if stats.Messages != 102 || stats.Dropped != 1 {
+		t.Errorf("expected 102 messages and 1 dropped, got %d and %d", stats.Messages, stats.Dropped)
+	}
+	if len(network.messages()) != 100 {
+		t.Errorf("expected 100 blocks in flight, got %d", len(network.messages()))
+	}
+
+	// Invalid links
+	invalid := []NetworkConfig{
+		{Default: Link{Latency: -1}},
+		{Default: Link{Distribution: "pareto"}},
+		{Default: Link{DropProbability: 2}},
+		{Links: []LinkConfig{{From: 3, To: 0}}},
+		{Links: []LinkConfig{{From: Controller, To: Controller}}},
+		{Partitions: Partitions{{Start: 10, Duration: 0, Groups: [][]int{{1}}}}},
+		{Partitions: Partitions{{Start: 10, Duration: 5}}},
+		{Partitions: Partitions{{Start: 10, Duration: 5, Groups: [][]int{{1, 2}, {2}}}}},
+		{Partitions: Partitions{{Start: 10, Duration: 5, Groups: [][]int{{3}}}}},
+	}
+	for _, config := range invalid {
+		if config.validate(2) == nil {
+			t.Errorf("expected error for %+v", config)
+		}
+	}
+}
+
+func runNetwork(config *Config, period time.Duration) *Simulation {
+
+	eventClock := clock.NewEventClock()
+	simulation := &Simulation{}
+	simulation.Init(config, eventClock)
+	simulation.Start()
+	eventClock.Advance(period)
+	simulation.Stop()
+
+	return simulation
+}
+
+func TestNetworkSimulation(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 4
+	config.DebugShard = 0
+	config.Network.Default = Link{Latency: 500, Jitter: 1000, Distribution: Exponential, DropProbability: 0.05, Bandwidth: 10000}
+
+	simulation := runNetwork(&config, 2*time.Minute)
+
+	// Same seed gives the same run
+	if !reflect.DeepEqual(runNetwork(&config, 2*time.Minute).DAG(), simulation.DAG()) {
+		t.Errorf("block trees differ between runs with the same seed")
+	}
+
+	for actor := 0; actor <= config.ShardCount; actor++ {
+		stats := simulation.NetworkStats(actor)
+		if stats.Messages == 0 {
+			t.Fatalf("actor %d sent no messages", actor)
+		}
+		if stats.MeanDelay() < 500*time.Millisecond {
+			t.Errorf("actor %d: mean delay %v below latency", actor, stats.MeanDelay())
+		}
+	}
+
+	// Blocks following a lost block wait for their parent
+	received := 0
+	for actor := 1; actor <= config.ShardCount; actor++ {
+		received += orphanStats(simulation.Shard(actor).chains).Received
+	}
+	if received == 0 {
+		t.Errorf("expected orphan blocks")
+	}
+
+	// Messages in flight and orphans survive a snapshot
+	snapshot := simulation.Snapshot()
+	if len(snapshot.InFlight) == 0 {
+		t.Fatalf("expected messages in flight")
+	}
+
+	var buffer bytes.Buffer
+	if err := snapshot.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadSnapshot(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredClock := clock.NewEventClockAt(decoded.Time)
+	restored := Simulation{}
+	if err := restored.Restore(decoded, restoredClock); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Snapshot(), snapshot) {
+		t.Errorf("snapshot of restored simulation differs")
+	}
+
+	// Messages in flight are delivered after restoring
+	height := restored.Beacon().Finalisation().Height
+	restored.Start()
+	restoredClock.Advance(time.Minute)
+	restored.Stop()
+
+	for _, message := range restored.Snapshot().InFlight {
+		if message.At <= snapshot.Time {
+			t.Errorf("message %d-%d at %v not delivered", message.From, message.To, message.At)
+		}
+	}
+	if restored.Beacon().Finalisation().Height <= height {
+		t.Errorf("restored simulation did not finalise, height %d", height)
+	}
+}
+
+func TestNetworkPartition(t *testing.T) {
+
+	var partitions Partitions
+	if err := partitions.Set("30:60:1,2/3,4"); err != nil {
+		t.Fatal(err)
+	}
+	if partitions[0].String() != "30:60:1,2/3,4" {
+		t.Errorf("expected 30:60:1,2/3,4, got %s", partitions[0].String())
+	}
+	for _, value := range []string{"30:60", "a:60:1", "30:60:1,b"} {
+		if (&Partitions{}).Set(value) == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+
+	partition := &partitions[0]
+	if !partition.separates(1, 3) || partition.separates(1, 2) || !partition.separates(0, 1) || partition.separates(Controller, 3) {
+		t.Errorf("unexpected groups of %s", partition.String())
+	}
+
+	config := DefaultConfig()
+	config.Seed = 5
+	config.DebugShard = 0
+	config.Network.Partitions = partitions
+
+	// Shard 1 does not receive blocks of shard 3 while partitioned
+	eventClock := clock.NewEventClock()
+	simulation := Simulation{}
+	simulation.Init(&config, eventClock)
+	simulation.Start()
+	eventClock.Advance(80 * time.Second)
+	simulation.Stop()
+
+	inserted := func(from time.Duration, to time.Duration) int {
+		count := 0
+		var walk func(chainBlock *chain.ChainBlock)
+		walk = func(chainBlock *chain.ChainBlock) {
+			x := chainBlock.Coordinate().X
+			if x >= float32(from.Seconds()*chain.PixelsPerSecond) && x < float32(to.Seconds()*chain.PixelsPerSecond) {
+				count++
+			}
+			for _, child := range chainBlock.Children() {
+				walk(child)
+			}
+		}
+		walk(simulation.Shard(1).Chain(3).GenesisBlock())
+		return count
+	}
+
+	if count := inserted(31*time.Second, 80*time.Second); count != 0 {
+		t.Errorf("shard 1 received %d blocks of shard 3 while partitioned", count)
+	}
+	if simulation.NetworkStats(3).Held == 0 {
+		t.Errorf("expected messages of shard 3 held by the partition")
+	}
+
+	// Held blocks arrive when the partition heals
+	simulation.Start()
+	eventClock.Advance(40 * time.Second)
+	simulation.Stop()
+
+	if count := inserted(90*time.Second, 91*time.Second); count == 0 {
+		t.Errorf("expected blocks of shard 3 delivered when the partition heals")
+	}
+}
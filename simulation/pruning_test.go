@@ -0,0 +1,60 @@
+package simulation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func runPruning(config *Config) *Simulation {
+
+	eventClock := clock.NewEventClock()
+	simulation := &Simulation{}
+	simulation.Init(config, eventClock)
+	simulation.Start()
+	eventClock.Advance(5 * time.Minute)
+	simulation.Stop()
+
+	return simulation
+}
+
+func TestPruning(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 6
+	config.DebugShard = 0
+
+	full := runPruning(&config)
+
+	config.Pruning = true
+	pruned := runPruning(&config)
+
+	// Pruning other block trees does not change the own block tree
+	if !reflect.DeepEqual(pruned.DAG(), full.DAG()) {
+		t.Errorf("own block trees differ with pruning")
+	}
+
+	for i := 1; i <= config.ShardCount; i++ {
+		for j := 1; j <= config.ShardCount; j++ {
+			if i == j {
+				continue
+			}
+
+			fullBlocks, _ := full.Shard(i).Chain(j).CountBlocks()
+			prunedBlocks, _ := pruned.Shard(i).Chain(j).CountBlocks()
+			root := pruned.Shard(i).Chain(j).GenesisBlock()
+
+			if root.Parent() != nil || root != pruned.Shard(i).Chain(j).LastFinalisedBlock() {
+				t.Errorf("shard %d: block tree of shard %d not pruned up to the last finalised block", i, j)
+			}
+			if prunedBlocks+pruned.Shard(i).Chain(j).Pruned() < fullBlocks {
+				t.Errorf("shard %d: block tree of shard %d lost blocks", i, j)
+			}
+			if prunedBlocks >= fullBlocks/2 {
+				t.Errorf("shard %d: block tree of shard %d has %d of %d blocks", i, j, prunedBlocks, fullBlocks)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+// Replay rebuilds the view of every shard from a recorded trace, instead of running the shard goroutines.
+// Every shard only handles its own received blocks, finalisations and generated transactions.
+type Replay struct {
+	config   *Config
+	events   []trace.Event
+	position int
+	clock    *clock.ManualClock
+	shards   []Shard
+}
+
+// Init replay of the events, the shard count is taken from the trace. Error if the configuration
+// does not fit the shard count, e.g. a fork-choice rule per shard for fewer shards.
+func (replay *Replay) Init(config *Config, events []trace.Event) error {
+
+	for _, event := range events {
+		if event.Actor > config.ShardCount {
+			config.ShardCount = event.Actor
+		}
+		if event.Shard > config.ShardCount {
+			config.ShardCount = event.Shard
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("replay of %d shards: %v", config.ShardCount, err)
+	}
+
+	replay.config = config
+	replay.events = events
+	replay.reset()
+	return nil
+}
+
+// Restart from an empty block tree for every shard.
+func (replay *Replay) reset() {
+
+	replay.position = 0
+	replay.clock = clock.NewManualClock()
+	replay.shards = make([]Shard, replay.config.ShardCount+1)
+
+	for i := 1; i <= replay.config.ShardCount; i++ {
+		replay.shards[i] = Shard{
+			id:     i,
+			config: replay.config,
+			random: rand.New(rand.NewSource(replay.config.Seed)),
+			clock:  replay.clock,
+		}
+		replay.shards[i].init()
+	}
+}
+
+// Apply next event, returns false at the end of the trace.
+func (replay *Replay) Step() bool {
+
+	if replay.position >= len(replay.events) {
+		return false
+	}
+
+	event := &replay.events[replay.position]
+	replay.position++
+
+	if event.Time > replay.clock.Now() {
+		replay.clock.Advance(event.Time - replay.clock.Now())
+	}
+
+	if event.Actor < 1 || event.Actor > replay.config.ShardCount {
+		return true
+	}
+	shard := &replay.shards[event.Actor]
+
+	switch event.Type {
+	case trace.BlockReceived:
+		shard.receiveBlock(event.Block)
+	case trace.FinalisationReceived:
+		shard.receiveFinalisation(event.Finalisation)
+	case trace.TransactionGenerated:
+		shard.txOutPool = append(shard.txOutPool, event.Transaction)
+	}
+
+	return true
+}
+
+// Undo last event by replaying the trace up to the previous event.
+func (replay *Replay) StepBack() {
+	if replay.position > 0 {
+		replay.Seek(replay.position - 1)
+	}
+}
+
+// Replay the trace up to position (number of applied events).
+func (replay *Replay) Seek(position int) {
+
+	if position < replay.position {
+		replay.reset()
+	}
+
+	for replay.position < position && replay.Step() {
+	}
+}
+
+// Apply events of the next time period.
+func (replay *Replay) Advance(period time.Duration) {
+
+	until := replay.clock.Now() + period
+
+	for replay.position < len(replay.events) && replay.events[replay.position].Time <= until {
+		replay.Step()
+	}
+
+	if until > replay.clock.Now() {
+		replay.clock.Advance(until - replay.clock.Now())
+	}
+}
+
+// Number of applied events
+func (replay *Replay) Position() int {
+	return replay.position
+}
+
+// Number of events in the trace
+func (replay *Replay) Len() int {
+	return len(replay.events)
+}
+
+// Whether all events are applied
+func (replay *Replay) Done() bool {
+	return replay.position >= len(replay.events)
+}
+
+func (replay *Replay) Config() *Config {
+	return replay.config
+}
+
+func (replay *Replay) Clock() clock.Clock {
+	return replay.clock
+}
+
+// Shard with identifier 1..ShardCount
+func (replay *Replay) Shard(id int) *Shard {
+	return &replay.shards[id]
+}
+
+// View of the block trees of shard id
+func (replay *Replay) View(id int) *ShardView {
+	return replay.shards[id].view()
+}
+
+// DAG of the block tree of every shard as seen by the shard itself.
+func (replay *Replay) DAG() chain.DAG {
+
+	chains := make([]*chain.Chain, len(replay.shards))
+	for i := 1; i < len(replay.shards); i++ {
+		chains[i] = replay.shards[i].Chain(i)
+	}
+
+	return chain.NewDAG(chains)
+}
@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+func TestReplay(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 3
+	config.DebugShard = 0
+	config.ProbabilityBuildOnLongestChain = 0.5
+
+	// Record run
+	var buffer bytes.Buffer
+	log := trace.NewLog(&buffer)
+
+	eventClock := clock.NewEventClock()
+	simulation := Simulation{}
+	simulation.Init(&config, eventClock)
+	simulation.Record(log)
+	simulation.Start()
+	eventClock.Advance(2 * time.Minute)
+	simulation.Stop()
+
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := trace.Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay rebuilds the block tree of every shard
+	replayConfig := DefaultConfig()
+	replayConfig.DebugShard = 0
+	replayConfig.ShardCount = 2
+
+	// A fork-choice rule per shard of the configured shards does not fit the trace
+	replayConfig.ForkChoice = ForkChoices{chain.LongestChain, chain.LongestChain}
+	replay := Replay{}
+	if err := replay.Init(&replayConfig, events); err == nil {
+		t.Errorf("expected error for a fork-choice rule per shard of 2 shards")
+	}
+
+	replayConfig.ShardCount = 2
+	replayConfig.ForkChoice = ForkChoices{chain.LongestChain}
+	if err := replay.Init(&replayConfig, events); err != nil {
+		t.Fatal(err)
+	}
+	if replayConfig.ShardCount != config.ShardCount {
+		t.Fatalf("expected %d shards, got %d", config.ShardCount, replayConfig.ShardCount)
+	}
+
+	replay.Seek(replay.Len())
+	if !replay.Done() {
+		t.Fatalf("replay not done at position %d of %d", replay.Position(), replay.Len())
+	}
+	if !reflect.DeepEqual(replay.DAG(), simulation.DAG()) {
+		t.Errorf("replayed block trees differ from the simulation")
+	}
+
+	// Stepping back gives the same state as replaying up to the position
+	half := replay.Len() / 2
+	replay.Seek(half)
+	expected := replay.DAG()
+
+	replay.Seek(replay.Len())
+	replay.Seek(half + 1)
+	replay.StepBack()
+
+	if replay.Position() != half {
+		t.Fatalf("expected position %d, got %d", half, replay.Position())
+	}
+	if !reflect.DeepEqual(replay.DAG(), expected) {
+		t.Errorf("block trees differ after stepping back")
+	}
+}
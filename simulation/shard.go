@@ -0,0 +1,440 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+type Shard struct {
+	id           int
+	config       *Config
+	channels     Communication
+	random       *rand.Rand
+	clock        clock.Clock
+	blockTimer   <-chan time.Time
+	txTimer      <-chan time.Time
+	txOutPool    []*chain.Transaction
+	chains       []chain.Chain
+	finalisation chain.Finalisation
+	forkChoice   chain.ForkChoice
+	keys         *Keyring
+	metrics      *Metrics
+	slot         int
+	byzantine    *ByzantineShard
+	withheld     []chain.Block
+	misbehaved   map[string]int
+	censoring    *CensoringShard
+	censored     map[string]bool
+	recorder     *trace.Recorder
+}
+
+func (shard *Shard) init() {
+
+	// Init chains
+	shard.chains = make([]chain.Chain, shard.config.ShardCount+1)
+	for i, _ := range shard.chains {
+		shard.chains[i] = chain.Chain{}
+		shard.chains[i].Init(i, shard.clock)
+		shard.chains[i].SetOrphanTimeout(shard.config.orphanTimeout())
+
+		// Replayed shards have no keys and accept the recorded blocks
+		if shard.keys != nil {
+			shard.chains[i].SetVerifier(shard.keys)
+		}
+	}
+
+	// Fork-choice rule for block production
+	shard.forkChoice = shard.config.forkChoice(shard.id)
+
+	// Byzantine behaviour, nil for an honest shard
+	shard.byzantine = shard.config.Byzantine.shard(shard.id)
+	shard.misbehaved = make(map[string]int)
+
+	// Censoring strategy, nil for a shard processing all incoming transactions
+	shard.censoring = shard.config.Censoring.shard(shard.id)
+	shard.censored = make(map[string]bool)
+
+	// Init txPool
+	shard.txOutPool = make([]*chain.Transaction, 0)
+
+	// Init finalisation
+	shard.finalisation = chain.Finalisation{
+		Height:         0,
+		Blocks:         nil,
+		InconsistentTX: make([]*chain.Transaction, 0),
+	}
+
+	// Init timers
+	shard.blockTimer = shard.clock.After(shard.config.BlockGenerationPeriod.NextRandomTimePeriod(shard.random))
+	shard.txTimer = shard.clock.After(shard.config.TXGenerationPeriod.NextRandomTimePeriod(shard.random))
+}
+
+// Record events of the shard and its chains
+func (shard *Shard) setRecorder(recorder *trace.Recorder) {
+	shard.recorder = recorder
+	for i := range shard.chains {
+		shard.chains[i].SetRecorder(recorder)
+	}
+}
+
+func (shard *Shard) run() {
+
+	shard.Println("Launching shard")
+
+	state := Pause
+
+	for {
+
+		// While paused only commands and queries are handled, block until one arrives
+		if state == Pause {
+			select {
+			case command := <-shard.channels.control[shard.id]:
+				shard.Println("Received command:", command)
+				shard.clock.Done()
+				switch *command {
+				case Run:
+					state = Run
+				case Exit:
+					return
+				}
+			case query := <-shard.channels.queries[shard.id]:
+				query()
+			}
+			continue
+		}
+
+		select {
+		case command := <-shard.channels.control[shard.id]:
+			shard.Println("Received command:", command)
+			shard.clock.Done()
+			switch *command {
+			case Pause:
+				state = Pause
+			case Exit:
+				return
+			}
+		case query := <-shard.channels.queries[shard.id]:
+			query()
+
+		case block := <-shard.channels.blocks[shard.id]:
+			shard.receiveBlock(block)
+			shard.clock.Done()
+
+		case finalisation := <-shard.channels.finalisation[shard.id]:
+			shard.receiveFinalisation(finalisation)
+			shard.clock.Done()
+
+		case <-shard.blockTimer:
+			shard.generateBlock()
+			shard.blockTimer = shard.clock.After(shard.config.BlockGenerationPeriod.NextRandomTimePeriod(shard.random))
+			shard.clock.Done()
+
+		case <-shard.txTimer:
+			shard.generateTransactions()
+			shard.txTimer = shard.clock.After(shard.config.TXGenerationPeriod.NextRandomTimePeriod(shard.random))
+			shard.clock.Done()
+		}
+	}
+}
+
+func (shard *Shard) receiveBlock(block *chain.Block) {
+
+	shard.Println(fmt.Sprintf("Received block from  %d.",  block.Shard))
+	shard.recorder.BlockReceived(block)
+
+	// Add block
+	shard.chains[block.Shard].Insert(block)
+
+	// Update shard block tree
+	shard.updateBlockTree()
+
+}
+
+func (shard *Shard) receiveFinalisation(finalisation *chain.Finalisation) {
+
+	shard.recorder.FinalisationReceived(finalisation)
+
+	// Only accept finalisations voted by a supermajority of the beacon committee
+	if shard.keys != nil {
+		if err := shard.keys.VerifyFinalisation(finalisation); err != nil {
+			shard.Println("Rejected finalisation:", err)
+			return
+		}
+	}
+
+	// Finalise blocks
+	for _, block := range finalisation.Blocks {
+
+		// Remove finalised transactions from TX Out Pool
+		if shard.id == block.Shard {
+			finalisedTXOutList := shard.chains[shard.id].GetTXOutList(block.Hash)
+
+			txOutPool := chain.NewTxSet(shard.txOutPool)
+			for _, finalisedTX := range finalisedTXOutList {
+				txOutPool.Remove(finalisedTX)
+			}
+			shard.txOutPool = txOutPool.List()
+		}
+
+		// Finalise blocks
+		shard.chains[block.Shard].Finalise(block.Hash)
+
+		// Prune other shards
+		if shard.config.Pruning && shard.id != block.Shard {
+			shard.chains[block.Shard].Prune(block.Hash)
+		}
+	}
+
+	shard.finalisation = *finalisation
+
+	// Update BlockTree
+	shard.updateBlockTree()
+}
+
+func (shard *Shard) generateBlock() {
+
+	// Every block period is a slot, also when no block is produced
+	shard.slot++
+
+	// Probability finalisation fails
+	if shard.random.Float64() > shard.config.BlockGenerationProbability {
+
+		shard.Println("Skip block generation.")
+		return
+	}
+
+	// Get candidate chains to build block upon, best first
+	candidateParents := shard.forkChoice.Candidates(&shard.chains[shard.id], 3)
+
+	// Random 'select the best chain' to simulate forks
+	parentChain := candidateParents[0]
+	if shard.random.Float64() > shard.config.ProbabilityBuildOnLongestChain {
+		if shard.random.Float64() <= 0.5 {
+			parentChain = candidateParents[1]
+		} else {
+			parentChain = candidateParents[2]
+		}
+	}
+
+	// Include some IN transactions
+	txOutOthersSet := chain.NewTxSet(shard.getOtherShardsTxOutList())
+	processedTxIn := shard.chains[shard.id].GetTXInList(parentChain.Block().Hash)
+
+	for _, txOut := range processedTxIn {
+		txOutOthersSet.Remove(txOut)
+	}
+	txOutOthers := txOutOthersSet.List()
+
+	// Censoring shards ignore some incoming transactions
+	if shard.censoring != nil {
+		txOutOthers = shard.uncensored(txOutOthers)
+	}
+
+	numberOfTxIn := minOf(len(txOutOthers), shard.config.BlockTxInNumber.NextRandomInt(shard.random))
+
+	txInList := make([]*chain.Transaction, numberOfTxIn)
+
+	j := 0
+	for _, i := range shard.random.Perm(len(txOutOthers)) {
+		if j == numberOfTxIn {
+			break;
+		}
+		txInList[j] = txOutOthers[i]
+		j++
+	}
+
+	// Include some OUT transactions
+	availableTxOutSet := chain.NewTxSet(shard.txOutPool)
+	processedTxOut := shard.chains[shard.id].GetTXOutList(parentChain.Block().Hash)
+
+	for _, txOut := range processedTxOut {
+		availableTxOutSet.Remove(txOut)
+	}
+	availableTxOut := availableTxOutSet.List()
+
+	numberOfTxOut := minOf(len(availableTxOut), shard.config.BlockTxOutNumber.NextRandomInt(shard.random))
+
+	txOutList := make([]*chain.Transaction, numberOfTxOut)
+	j = 0
+	for _, i := range shard.random.Perm(len(availableTxOut)) {
+		if j == numberOfTxOut {
+			break;
+		}
+		txOutList[j] = availableTxOut[i]
+		j++
+	}
+
+	// Publish block
+	block := chain.Block{
+		Shard:      shard.id,
+		Hash:       []byte{},
+		ParentHash: parentChain.Block().Hash,
+		Slot:       shard.slot,
+		TXIn:       txInList,
+		TXOut:      txOutList,
+	}
+	shard.sign(&block)
+
+	// Record first inclusion of outgoing transactions in the source shard, of incoming ones in the target shard
+	shard.metrics.RecordIncluded(&block, shard.clock.Now())
+
+	// Byzantine committees misbehave in some of their blocks
+	if shard.byzantine != nil && shard.random.Float64() < shard.byzantine.Probability {
+		shard.misbehave(block)
+		return
+	}
+
+	shard.publishBlock(block)
+}
+
+// Hash and sign block as the proposer of its slot
+func (shard *Shard) sign(block *chain.Block) {
+	shard.keys.Proposer(shard.id, block.Slot).sign(block)
+}
+
+// Update valid block tree, based on 'Valid block trees'
+// 'Magic Fork Choice Rule'
+func (shard *Shard) updateBlockTree() {
+
+	txOutList := shard.getOtherShardsTxOutList()
+
+	// Update consistency of shard chain.
+	shard.chains[shard.id].UpdateConsistency(txOutList)
+}
+
+func (shard *Shard) getOtherShardsTxOutList() []*chain.Transaction {
+
+	txOutList := make([]*chain.Transaction, 0)
+
+	// Get all finalised inconsistent transactions
+	for _, tx := range shard.finalisation.InconsistentTX {
+		if tx.TargetShard == shard.id {
+			txOutList = append(txOutList, tx)
+		}
+	}
+
+	// For all other shards, get all outgoing TX related to this shard.
+	for i := 1; i <= shard.config.ShardCount; i++ {
+
+		if i != shard.id {
+
+			shardTxOut := shard.chains[i].GetLongestChainTXOutList()
+
+			for _, tx := range shardTxOut {
+				if tx.TargetShard == shard.id {
+					txOutList = append(txOutList, tx)
+				}
+			}
+		}
+
+	}
+
+	return txOutList
+}
+
+func (shard *Shard) generateTransactions() {
+
+	// Create transactions
+	for i := 1; i <= shard.config.TXGenerationNumber.NextRandomInt(shard.random); i++ {
+
+		if len(shard.txOutPool) < shard.config.TXPoolSize {
+
+			// Random destination
+			shardRange := shard.config.ShardRange()
+			destShard := shardRange.NextRandomInt(shard.random)
+			for destShard == shard.id {
+				destShard = shardRange.NextRandomInt(shard.random)
+			}
+
+			tx := chain.Transaction{
+				SourceShard: shard.id,
+				TargetShard: destShard,
+				Data:        fmt.Sprintf("%d-%x", shard.id, shard.random.Uint32()),
+				Created:     shard.clock.Now(),
+			}
+
+			tx.SetHash()
+			shard.recorder.TransactionGenerated(&tx)
+
+			shard.txOutPool = append(shard.txOutPool, &tx)
+
+			shard.Println("Generated new transactions, txOutPool size:", len(shard.txOutPool))
+		}
+	}
+
+}
+
+// Identifier of the shard
+func (shard *Shard) ID() int {
+	return shard.id
+}
+
+// Block tree of shard i as seen by this shard
+func (shard *Shard) Chain(i int) *chain.Chain {
+	return &shard.chains[i]
+}
+
+func (shard *Shard) UpdateVisualisation() {
+
+	for i := 1; i <= shard.config.ShardCount; i++ {
+		shard.chains[i].UpdateVisualisation(shard.id == i)
+	}
+}
+
+// Copy of the block trees of a shard, read by observers while the shard continues.
+type ShardView struct {
+	id     int
+	chains []*chain.Chain
+}
+
+// Identifier of the shard
+func (view *ShardView) ID() int {
+	return view.id
+}
+
+// Block tree of shard i as seen by the shard when the view was taken
+func (view *ShardView) Chain(i int) *chain.Chain {
+	return view.chains[i]
+}
+
+// View of the block trees with updated visualisation, taken on the goroutine of the shard
+func (shard *Shard) view() *ShardView {
+
+	view := &ShardView{
+		id:     shard.id,
+		chains: make([]*chain.Chain, len(shard.chains)),
+	}
+	for i := range shard.chains {
+		view.chains[i] = shard.chains[i].Copy()
+	}
+
+	// Visualisation is updated on the copies, the shard state is left untouched
+	for i := 1; i <= shard.config.ShardCount; i++ {
+		view.chains[i].UpdateVisualisation(shard.id == i)
+	}
+	return view
+}
+
+func (shard *Shard) Println(a ...interface{}) {
+	if shard.id == shard.config.DebugShard {
+		fmt.Printf("[shard %d] ", shard.id)
+		fmt.Println(a...)
+	}
+}
+
+func minOf(vars ...int) int {
+	min := vars[0]
+
+	for _, i := range vars {
+		if min > i {
+			min = i
+		}
+	}
+
+	return min
+}
@@ -0,0 +1,274 @@
+// Package simulation runs the beacon and shard actors of a sharded blockchain.
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+// Simulation bundles the beacon, the shards and their communication channels.
+// Front-ends (visualiser, headless runner) drive the simulation through it.
+type Simulation struct {
+	config   *Config
+	channels Communication
+	clock    clock.Clock
+	beacon   Beacon
+	metrics  Metrics
+	keys     Keyring
+	shards   []Shard
+	actors   sync.WaitGroup
+	running  bool
+}
+
+// Init simulation, every actor gets its own random source derived from the seed.
+func (simulation *Simulation) Init(config *Config, clock clock.Clock) {
+	simulation.init(config, clock, config.Seed)
+}
+
+func (simulation *Simulation) init(config *Config, clock clock.Clock, seed int64) {
+
+	seeds := rand.New(rand.NewSource(seed))
+	simulation.config = config
+	simulation.clock = clock
+
+	// Establish communication channels
+	simulation.channels.init(config, clock)
+
+	simulation.metrics.init(config.ShardCount)
+
+	// Validator identities follow from the configured seed, also for a restored simulation
+	simulation.keys.init(config.ShardCount, config.CommitteeSize, config.BeaconCommitteeSize, config.Seed)
+
+	// Create beacon
+	simulation.beacon = Beacon{
+		config:   config,
+		channels: &simulation.channels,
+		random:   rand.New(rand.NewSource(seeds.Int63())),
+		clock:    clock,
+		metrics:  &simulation.metrics,
+		keys:     &simulation.keys,
+	}
+	simulation.beacon.init()
+
+	// Create shards
+	simulation.shards = make([]Shard, config.ShardCount+1)
+	for i := 1; i <= config.ShardCount; i++ {
+		simulation.shards[i] = Shard{
+			id:       i,
+			config:   config,
+			channels: simulation.channels,
+			random:   rand.New(rand.NewSource(seeds.Int63())),
+			clock:    clock,
+			keys:     &simulation.keys,
+			metrics:  &simulation.metrics,
+		}
+		simulation.shards[i].init()
+	}
+
+	// Random sources of the network senders
+	simulation.channels.network.seed(seeds)
+}
+
+// Record events of the beacon and every shard in the log, call before Start.
+func (simulation *Simulation) Record(log *trace.Log) {
+	simulation.beacon.setRecorder(log.Recorder(trace.Beacon, simulation.clock))
+	for i := 1; i <= simulation.config.ShardCount; i++ {
+		simulation.shards[i].setRecorder(log.Recorder(i, simulation.clock))
+	}
+}
+
+// Launch beacon and shard goroutines and start the simulation.
+func (simulation *Simulation) Start() {
+
+	simulation.actors.Add(simulation.config.ShardCount + 1)
+
+	go func() {
+		defer simulation.actors.Done()
+		simulation.beacon.run()
+	}()
+
+	for i := 1; i <= simulation.config.ShardCount; i++ {
+		shard := &simulation.shards[i]
+		go func() {
+			defer simulation.actors.Done()
+			shard.run()
+		}()
+	}
+
+	simulation.channels.signal(Run)
+	simulation.running = true
+}
+
+// Stop all goroutines and wait until they have exited, messages in flight stay in the network.
+func (simulation *Simulation) Stop() {
+	simulation.channels.signal(Exit)
+	simulation.actors.Wait()
+	simulation.running = false
+}
+
+// Resume a paused simulation, the command travels over the network.
+func (simulation *Simulation) Resume() {
+	simulation.channels.broadCastCommand(Run)
+}
+
+// Pause the beacon and all shards, the command travels over the network.
+func (simulation *Simulation) Pause() {
+	simulation.channels.broadCastCommand(Pause)
+}
+
+func (simulation *Simulation) Config() *Config {
+	return simulation.config
+}
+
+func (simulation *Simulation) Clock() clock.Clock {
+	return simulation.clock
+}
+
+// Beacon of a stopped simulation, observers of a running simulation use ShardStakes.
+func (simulation *Simulation) Beacon() *Beacon {
+	return &simulation.beacon
+}
+
+// Shard with identifier 1..ShardCount of a stopped simulation, observers of a running simulation use View.
+func (simulation *Simulation) Shard(id int) *Shard {
+	return &simulation.shards[id]
+}
+
+// Run query on the goroutine of actor while the simulation runs, or directly when it is stopped.
+// Start, Stop and queries are called from the goroutine controlling the simulation.
+func (simulation *Simulation) query(actor int, query func()) {
+	if !simulation.running {
+		query()
+		return
+	}
+	simulation.channels.query(actor, query)
+}
+
+// View of the block trees of shard id, can be read while the simulation runs.
+func (simulation *Simulation) View(id int) *ShardView {
+	var view *ShardView
+	simulation.query(id, func() {
+		view = simulation.shards[id].view()
+	})
+	return view
+}
+
+// Stake of every shard committee (index shard), can be read while the simulation runs.
+func (simulation *Simulation) ShardStakes() []int64 {
+	stakes := make([]int64, simulation.config.ShardCount+1)
+	simulation.query(0, func() {
+		for shard := 1; shard <= simulation.config.ShardCount; shard++ {
+			stakes[shard] = simulation.beacon.validators.ShardStake(shard)
+		}
+	})
+	return stakes
+}
+
+func (simulation *Simulation) Metrics() *Metrics {
+	return &simulation.metrics
+}
+
+// Traffic sent by actor over delaying links, Controller for the commands
+func (simulation *Simulation) NetworkStats(actor int) NetworkStats {
+	network := simulation.channels.network
+	return network.traffic(network.sender(actor))
+}
+
+// DAG of the block tree of every shard as seen by the shard itself, so validity is known.
+func (simulation *Simulation) DAG() chain.DAG {
+
+	chains := make([]*chain.Chain, simulation.config.ShardCount+1)
+	for i := 1; i <= simulation.config.ShardCount; i++ {
+		chains[i] = simulation.View(i).Chain(i)
+	}
+
+	return chain.NewDAG(chains)
+}
+
+// Print summary of the beacon and every shard chain, as seen by the shard itself.
+func (simulation *Simulation) PrintSummary() {
+
+	fmt.Println("Simulation summary")
+	orphans := orphanStats(simulation.beacon.chains)
+	fmt.Printf("[beacon] finalisation height: %d - inconsistent TX: %d - orphans: %d - expired: %d - rejected: %d\n",
+		simulation.beacon.finalisation.Height, len(simulation.beacon.finalisation.InconsistentTX), orphans.Received, orphans.Expired,
+		rejected(simulation.beacon.chains))
+
+	// Finalisation rounds of the beacon committee and the votes not cast
+	rounds := simulation.beacon.rounds
+	fmt.Printf("[beacon] committee: %d - rounds: %d - finalised: %d - split: %d - too few votes: %d - absent: %d - behind: %d\n",
+		len(simulation.beacon.committee), rounds.Rounds, rounds.Finalised, rounds.Split, rounds.TooFewVotes(), rounds.Absent, rounds.Behind)
+
+	for i := 1; i <= simulation.config.ShardCount; i++ {
+		shard := &simulation.shards[i]
+		chain := &shard.chains[i]
+
+		blocks, invalid := chain.CountBlocks()
+		longestChain := chain.GetLongestChains(1, true)[0]
+
+		orphans := orphanStats(shard.chains)
+
+		pruned := 0
+		for j := range shard.chains {
+			pruned += shard.chains[j].Pruned()
+		}
+
+		fmt.Printf("[shard %d] blocks: %d - invalid: %d - canonical height: %d - finalised height: %d - TX out pool: %d - stake: %d - orphans: %d - expired: %d - pruned: %d - rejected: %d - fork choice: %s\n",
+			i, blocks, invalid, longestChain.Height(), chain.LastFinalisedBlock().Height(), len(shard.txOutPool),
+			simulation.beacon.validators.ShardStake(i), orphans.Received, orphans.Expired, pruned, rejected(shard.chains), shard.forkChoice.Name())
+
+		// Misbehaving blocks of a byzantine shard per behaviour
+		if shard.byzantine != nil {
+			fmt.Printf("[shard %d] byzantine", i)
+			for _, behaviour := range shard.byzantine.Behaviours {
+				fmt.Printf(" - %s: %d", behaviour, shard.misbehaved[behaviour])
+			}
+			fmt.Printf(" - withheld: %d\n", len(shard.withheld))
+		}
+
+		// Validators of the shard which signed two blocks for a slot, as seen by the beacon
+		if equivocations := simulation.beacon.chains[i].Equivocations(); len(equivocations) > 0 {
+			fmt.Printf("[shard %d] equivocations: %d - validators:", i, len(equivocations))
+			for _, equivocation := range equivocations {
+				fmt.Printf(" %s@%d", equivocation.Validator, equivocation.Slot)
+			}
+			fmt.Println()
+		}
+
+		// Incoming transactions a censoring shard ignored
+		if shard.censoring != nil {
+			fmt.Printf("[shard %d] censoring %s - censored TX: %d\n", i, shard.censoring.String(), len(shard.censored))
+		}
+	}
+
+	simulation.channels.network.printReport()
+	simulation.metrics.PrintReport()
+	simulation.metrics.PrintInconsistencyReport()
+}
+
+// Blocks rejected by all block trees of an actor
+func rejected(chains []chain.Chain) int {
+	total := 0
+	for i := range chains {
+		total += chains[i].Rejected()
+	}
+	return total
+}
+
+// Orphan counts of all block trees of an actor
+func orphanStats(chains []chain.Chain) chain.OrphanStats {
+	total := chain.OrphanStats{}
+	for i := range chains {
+		stats := chains[i].OrphanStats()
+		total.Received += stats.Received
+		total.Adopted += stats.Adopted
+		total.Expired += stats.Expired
+		total.Pending += stats.Pending
+	}
+	return total
+}
@@ -0,0 +1,226 @@
+package simulation
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+	"github.com/sjoerdwels/Guaranteed-TX/trace"
+)
+
+// Whether block is ancestor of, or equal to, descendant
+func isAncestor(block *chain.ChainBlock, descendant *chain.ChainBlock) bool {
+	for ; descendant != nil; descendant = descendant.Parent() {
+		if descendant == block {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify the finalised blocks of a chain form a single valid path from genesis up to the last finalised block.
+func checkFinalisedPath(t *testing.T, name string, blockTree *chain.Chain, isShardChain bool) {
+	t.Helper()
+
+	var walk func(chainBlock *chain.ChainBlock)
+	walk = func(chainBlock *chain.ChainBlock) {
+
+		finalisedChildren := 0
+		for _, child := range chainBlock.Children() {
+			if child.Finalised() {
+				finalisedChildren++
+				if !chainBlock.Finalised() {
+					t.Fatalf("%s: finalised block at height %d has parent which is not finalised", name, child.Height())
+				}
+			}
+			walk(child)
+		}
+
+		if finalisedChildren > 1 {
+			t.Fatalf("%s: block at height %d has %d finalised children", name, chainBlock.Height(), finalisedChildren)
+		}
+		if isShardChain && chainBlock.Finalised() && !chainBlock.Valid() {
+			t.Fatalf("%s: finalised block at height %d is invalid", name, chainBlock.Height())
+		}
+		if chainBlock.Finalised() && chainBlock.Height() > blockTree.LastFinalisedBlock().Height() {
+			t.Fatalf("%s: finalised block at height %d beyond last finalised block", name, chainBlock.Height())
+		}
+	}
+	walk(blockTree.GenesisBlock())
+}
+
+// Collect hashes of the transactions in finalised blocks
+func finalisedTransactions(blockTree *chain.Chain) (map[string]bool, []*chain.Transaction) {
+
+	txOut := make(map[string]bool)
+	txIn := make([]*chain.Transaction, 0)
+
+	for chainBlock := blockTree.LastFinalisedBlock(); chainBlock != nil; chainBlock = chainBlock.Parent() {
+		for _, tx := range chainBlock.Block().TXOut {
+			txOut[tx.Hash] = true
+		}
+		txIn = append(txIn, chainBlock.Block().TXIn...)
+	}
+
+	return txOut, txIn
+}
+
+func TestPropertySimulation(t *testing.T) {
+
+	// Default rule, and a different rule on every shard
+	for _, forkChoice := range []ForkChoices{{chain.LongestChain}, chain.ForkChoiceRules} {
+		for seed := int64(1); seed <= 5; seed++ {
+
+			config := DefaultConfig()
+			config.Seed = seed
+			config.DebugShard = 0
+			config.ForkChoice = forkChoice
+
+			eventClock := clock.NewEventClock()
+			simulation := Simulation{}
+			simulation.Init(&config, eventClock)
+			simulation.Start()
+
+			lastFinalised := make([]*chain.ChainBlock, config.ShardCount+1)
+			for shard := 1; shard <= config.ShardCount; shard++ {
+				lastFinalised[shard] = simulation.Beacon().Chain(shard).LastFinalisedBlock()
+			}
+
+			// All actors are idle in between advancing the clock
+			for step := 0; step < 10; step++ {
+				eventClock.Advance(30 * time.Second)
+
+				// Finalisation never reverts
+				for shard := 1; shard <= config.ShardCount; shard++ {
+					finalised := simulation.Beacon().Chain(shard).LastFinalisedBlock()
+					if !isAncestor(lastFinalised[shard], finalised) {
+						t.Fatalf("%s seed %d: finalisation of shard %d reverted", forkChoice.String(), seed, shard)
+					}
+					lastFinalised[shard] = finalised
+				}
+			}
+
+			simulation.Stop()
+
+			for shard := 1; shard <= config.ShardCount; shard++ {
+				checkFinalisedPath(t, fmt.Sprintf("%s seed %d beacon shard %d", forkChoice.String(), seed, shard), simulation.Beacon().Chain(shard), false)
+				checkFinalisedPath(t, fmt.Sprintf("%s seed %d shard %d", forkChoice.String(), seed, shard), simulation.Shard(shard).Chain(shard), true)
+
+				// Every finalised TXIn has a matching finalised TXOut
+				_, txIn := finalisedTransactions(simulation.Beacon().Chain(shard))
+				for _, tx := range txIn {
+					txOut, _ := finalisedTransactions(simulation.Beacon().Chain(tx.SourceShard))
+					if !txOut[tx.Hash] {
+						t.Fatalf("%s seed %d: finalised TXIn of shard %d has no finalised TXOut in shard %d", forkChoice.String(), seed, shard, tx.SourceShard)
+					}
+				}
+
+				// Honest proposers sign one block per slot, which every actor accepts
+				if rejected := simulation.Beacon().Chain(shard).Rejected() + simulation.Shard(shard).Chain(shard).Rejected(); rejected != 0 {
+					t.Fatalf("%s seed %d: %d blocks of shard %d rejected", forkChoice.String(), seed, rejected, shard)
+				}
+				if equivocations := simulation.Beacon().Chain(shard).Equivocations(); len(equivocations) != 0 {
+					t.Fatalf("%s seed %d: validator %s of shard %d equivocated", forkChoice.String(), seed, equivocations[0].Validator, shard)
+				}
+			}
+		}
+	}
+}
+
+// Views can be taken while the actors run, run with -race to detect shared state.
+func TestViewWhileRunning(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 1
+	config.DebugShard = 0
+
+	eventClock := clock.NewEventClock()
+	simulation := Simulation{}
+	simulation.Init(&config, eventClock)
+	simulation.Start()
+
+	done := make(chan struct{})
+	go func() {
+		eventClock.Advance(2 * time.Minute)
+		close(done)
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		for shard := 1; shard <= config.ShardCount; shard++ {
+			view := simulation.View(shard)
+			checkFinalisedPath(t, fmt.Sprintf("view of shard %d", shard), view.Chain(shard), true)
+			view.Chain(shard).UpdateVisualisation(true)
+		}
+		simulation.DAG()
+		if stakes := simulation.ShardStakes(); len(stakes) != config.ShardCount+1 {
+			t.Fatalf("expected %d stakes, got %d", config.ShardCount+1, len(stakes))
+		}
+
+		// Leave the actors time to make progress, like a frame of the visualiser
+		time.Sleep(time.Millisecond)
+	}
+
+	simulation.Stop()
+
+	// View of a stopped simulation equals its state
+	for shard := 1; shard <= config.ShardCount; shard++ {
+		view := simulation.View(shard)
+		if view.Chain(shard).LastFinalisedBlock().Height() != simulation.Shard(shard).Chain(shard).LastFinalisedBlock().Height() {
+			t.Errorf("view of shard %d differs from stopped shard", shard)
+		}
+	}
+}
+
+// Actors on the real clock record a trace while views are taken, like the visualiser. Run with -race.
+func TestRecordWhileRunning(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 1
+	config.DebugShard = 0
+	config.FinalisationPeriod = BoundedRange{0, 1}
+	config.BlockGenerationPeriod = BoundedRange{0, 1}
+	config.TXGenerationPeriod = BoundedRange{0, 1}
+
+	var buffer bytes.Buffer
+	log := trace.NewLog(&buffer)
+
+	simulation := Simulation{}
+	simulation.Init(&config, clock.NewRealClock())
+	simulation.Record(log)
+	simulation.Start()
+
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); {
+		simulation.DAG()
+		simulation.ShardStakes()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	simulation.Stop()
+
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := trace.Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finalisations := 0
+	for _, event := range events {
+		if event.Type == trace.FinalisationProposed {
+			finalisations++
+		}
+	}
+	if finalisations == 0 || simulation.Beacon().Finalisation().Height != finalisations {
+		t.Errorf("expected %d finalisations recorded, got %d", simulation.Beacon().Finalisation().Height, finalisations)
+	}
+}
@@ -0,0 +1,351 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+// Snapshot of the full simulation state. Transactions are stored once and referenced by hash.
+type Snapshot struct {
+	Config        Config                `json:"config"`
+	Time          time.Duration         `json:"time"`
+	Transactions  []*chain.Transaction  `json:"transactions"`
+	Beacon        BeaconSnapshot        `json:"beacon"`
+	Shards        []ShardSnapshot       `json:"shards"`
+	Latencies     []LatencySnapshot     `json:"latencies"`
+	Stages        []StagesSnapshot      `json:"stages"`
+	Inconsistency InconsistencySnapshot `json:"inconsistency"`
+	InFlight      []MessageSnapshot     `json:"inFlight,omitempty"`
+}
+
+type BeaconSnapshot struct {
+	Chains        []chain.ChainSnapshot      `json:"chains"`
+	Finalisation  chain.FinalisationSnapshot `json:"finalisation"`
+	Validators    ValidatorsSnapshot         `json:"validators"`
+	PendingBlocks []chain.BlockSnapshot      `json:"pendingBlocks"`
+	Committee     []BeaconValidatorSnapshot  `json:"committee,omitempty"`
+	Rounds        CommitteeStats             `json:"rounds"`
+}
+
+// View of a beacon validator, blocks not yet visible to it become visible at time Visible
+type BeaconValidatorSnapshot struct {
+	Chains  []chain.ChainSnapshot  `json:"chains"`
+	Pending []PendingBlockSnapshot `json:"pending"`
+}
+
+type PendingBlockSnapshot struct {
+	Block   chain.BlockSnapshot `json:"block"`
+	Visible time.Duration       `json:"visible"`
+}
+
+// Block or finalisation travelling over the network, delivered at time At
+type MessageSnapshot struct {
+	From         int                         `json:"from"`
+	To           int                         `json:"to"`
+	At           time.Duration               `json:"at"`
+	Block        *chain.BlockSnapshot        `json:"block,omitempty"`
+	Finalisation *chain.FinalisationSnapshot `json:"finalisation,omitempty"`
+}
+
+type ShardSnapshot struct {
+	ID                   int                          `json:"id"`
+	Slot                 int                          `json:"slot"`
+	Chains               []chain.ChainSnapshot        `json:"chains"`
+	TXOutPool            []string                     `json:"txOutPool"`
+	Finalisation         chain.FinalisationSnapshot   `json:"finalisation"`
+	PendingBlocks        []chain.BlockSnapshot        `json:"pendingBlocks"`
+	PendingFinalisations []chain.FinalisationSnapshot `json:"pendingFinalisations"`
+	Withheld             []chain.BlockSnapshot        `json:"withheld,omitempty"`
+}
+
+// Snapshot of the stopped simulation, including undelivered messages. Start continues the simulation.
+func (simulation *Simulation) Snapshot() Snapshot {
+
+	table := chain.TransactionTable{}
+	beacon := &simulation.beacon
+
+	// Messages are either queued or in flight, not being delivered
+	network := simulation.channels.network
+	network.delivery.Lock()
+	defer network.delivery.Unlock()
+
+	snapshot := Snapshot{
+		Config: *simulation.config,
+		Time:   simulation.clock.Now(),
+		Beacon: BeaconSnapshot{
+			Chains:       snapshotChains(beacon.chains, table),
+			Finalisation: beacon.finalisation.Snapshot(table),
+			Validators:   beacon.validators.snapshot(),
+			Rounds:       beacon.rounds,
+		},
+		Shards:        make([]ShardSnapshot, 0, simulation.config.ShardCount),
+		Latencies:     simulation.metrics.snapshot(),
+		Stages:        simulation.metrics.stagesSnapshot(),
+		Inconsistency: simulation.metrics.inconsistencySnapshot(table),
+	}
+
+	blocks, _ := simulation.channels.pending(0)
+	snapshot.Beacon.PendingBlocks = snapshotBlocks(blocks, table)
+
+	for _, validator := range beacon.committee {
+		validatorSnapshot := BeaconValidatorSnapshot{
+			Chains:  snapshotChains(validator.chains, table),
+			Pending: make([]PendingBlockSnapshot, len(validator.pending)),
+		}
+		for i, pending := range validator.pending {
+			validatorSnapshot.Pending[i] = PendingBlockSnapshot{Block: chain.NewBlockSnapshot(pending.block, table), Visible: pending.visible}
+		}
+		snapshot.Beacon.Committee = append(snapshot.Beacon.Committee, validatorSnapshot)
+	}
+
+	for i := 1; i <= simulation.config.ShardCount; i++ {
+		shard := &simulation.shards[i]
+
+		shardSnapshot := ShardSnapshot{
+			ID:                   i,
+			Slot:                 shard.slot,
+			Chains:               snapshotChains(shard.chains, table),
+			TXOutPool:            table.Refs(shard.txOutPool),
+			Finalisation:         shard.finalisation.Snapshot(table),
+			PendingFinalisations: make([]chain.FinalisationSnapshot, 0),
+		}
+		for j := range shard.withheld {
+			shardSnapshot.Withheld = append(shardSnapshot.Withheld, chain.NewBlockSnapshot(&shard.withheld[j], table))
+		}
+
+		blocks, finalisations := simulation.channels.pending(i)
+		shardSnapshot.PendingBlocks = snapshotBlocks(blocks, table)
+		for _, finalisation := range finalisations {
+			shardSnapshot.PendingFinalisations = append(shardSnapshot.PendingFinalisations, finalisation.Snapshot(table))
+		}
+
+		snapshot.Shards = append(snapshot.Shards, shardSnapshot)
+	}
+
+	for _, message := range network.messages() {
+		messageSnapshot := MessageSnapshot{From: message.from, To: message.to, At: message.at}
+		if message.block != nil {
+			block := chain.NewBlockSnapshot(message.block, table)
+			messageSnapshot.Block = &block
+		} else {
+			finalisation := message.finalisation.Snapshot(table)
+			messageSnapshot.Finalisation = &finalisation
+		}
+		snapshot.InFlight = append(snapshot.InFlight, messageSnapshot)
+	}
+
+	snapshot.Transactions = table.List()
+
+	return snapshot
+}
+
+func snapshotChains(chains []chain.Chain, table chain.TransactionTable) []chain.ChainSnapshot {
+	snapshots := make([]chain.ChainSnapshot, len(chains))
+	for i := range chains {
+		snapshots[i] = chains[i].Snapshot(table)
+	}
+	return snapshots
+}
+
+func snapshotBlocks(blocks []*chain.Block, table chain.TransactionTable) []chain.BlockSnapshot {
+	snapshots := make([]chain.BlockSnapshot, len(blocks))
+	for i, block := range blocks {
+		snapshots[i] = chain.NewBlockSnapshot(block, table)
+	}
+	return snapshots
+}
+
+// Restore simulation from snapshot, the clock must continue at the time of the snapshot.
+// Random sources are derived from the seed and the time of the snapshot.
+func (simulation *Simulation) Restore(snapshot *Snapshot, clock clock.Clock) error {
+
+	config := &snapshot.Config
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if len(snapshot.Shards) != config.ShardCount {
+		return fmt.Errorf("expected %d shards, got %d", config.ShardCount, len(snapshot.Shards))
+	}
+
+	simulation.init(config, clock, config.Seed+int64(snapshot.Time))
+
+	table := chain.NewTransactionTable(snapshot.Transactions)
+
+	// Beacon
+	beacon := &simulation.beacon
+	if err := restoreChains(beacon.chains, snapshot.Beacon.Chains, table); err != nil {
+		return fmt.Errorf("beacon: %v", err)
+	}
+	finalisation, err := snapshot.Beacon.Finalisation.Restore(table)
+	if err != nil {
+		return fmt.Errorf("beacon: %v", err)
+	}
+	beacon.finalisation = *finalisation
+	if err := beacon.validators.restore(snapshot.Beacon.Validators); err != nil {
+		return err
+	}
+	if err := beacon.restoreCommittee(snapshot.Beacon, table); err != nil {
+		return fmt.Errorf("beacon: %v", err)
+	}
+	simulation.metrics.restore(snapshot.Latencies)
+	if err := simulation.metrics.restoreStages(snapshot.Stages); err != nil {
+		return err
+	}
+	if err := simulation.metrics.restoreInconsistency(snapshot.Inconsistency, table); err != nil {
+		return err
+	}
+
+	// Shards
+	for _, shardSnapshot := range snapshot.Shards {
+		if shardSnapshot.ID < 1 || shardSnapshot.ID > config.ShardCount {
+			return fmt.Errorf("unknown shard %d", shardSnapshot.ID)
+		}
+		shard := &simulation.shards[shardSnapshot.ID]
+
+		if err := restoreChains(shard.chains, shardSnapshot.Chains, table); err != nil {
+			return fmt.Errorf("shard %d: %v", shard.id, err)
+		}
+		if shard.txOutPool, err = table.Resolve(shardSnapshot.TXOutPool); err != nil {
+			return fmt.Errorf("shard %d: %v", shard.id, err)
+		}
+		finalisation, err := shardSnapshot.Finalisation.Restore(table)
+		if err != nil {
+			return fmt.Errorf("shard %d: %v", shard.id, err)
+		}
+		shard.finalisation = *finalisation
+		shard.slot = shardSnapshot.Slot
+
+		for i := range shardSnapshot.Withheld {
+			block, err := shardSnapshot.Withheld[i].Restore(table)
+			if err != nil {
+				return fmt.Errorf("shard %d: %v", shard.id, err)
+			}
+			shard.withheld = append(shard.withheld, *block)
+		}
+	}
+
+	// Undelivered messages
+	if err := simulation.restoreBlocks(0, snapshot.Beacon.PendingBlocks, table); err != nil {
+		return fmt.Errorf("beacon: %v", err)
+	}
+	for _, shardSnapshot := range snapshot.Shards {
+		if err := simulation.restoreBlocks(shardSnapshot.ID, shardSnapshot.PendingBlocks, table); err != nil {
+			return fmt.Errorf("shard %d: %v", shardSnapshot.ID, err)
+		}
+		for i := range shardSnapshot.PendingFinalisations {
+			finalisation, err := shardSnapshot.PendingFinalisations[i].Restore(table)
+			if err != nil {
+				return fmt.Errorf("shard %d: %v", shardSnapshot.ID, err)
+			}
+			simulation.channels.sendFinalisation(shardSnapshot.ID, finalisation)
+		}
+	}
+
+	// Messages in flight
+	for i := range snapshot.InFlight {
+		if err := simulation.restoreMessage(&snapshot.InFlight[i], table); err != nil {
+			return fmt.Errorf("message %d-%d: %v", snapshot.InFlight[i].From, snapshot.InFlight[i].To, err)
+		}
+	}
+
+	return nil
+}
+
+// Views of the beacon committee, snapshots without committee restore every view as the beacon chains
+func (beacon *Beacon) restoreCommittee(snapshot BeaconSnapshot, table chain.TransactionTable) error {
+
+	if len(snapshot.Committee) != len(beacon.committee) {
+		return fmt.Errorf("expected %d beacon validators, got %d", len(beacon.committee), len(snapshot.Committee))
+	}
+	beacon.rounds = snapshot.Rounds
+
+	for i, validator := range beacon.committee {
+		if err := restoreChains(validator.chains, snapshot.Committee[i].Chains, table); err != nil {
+			return fmt.Errorf("validator %s: %v", validator.identity.Name, err)
+		}
+		for _, pending := range snapshot.Committee[i].Pending {
+			block, err := pending.Block.Restore(table)
+			if err != nil {
+				return fmt.Errorf("validator %s: %v", validator.identity.Name, err)
+			}
+			validator.add(pendingBlock{block: block, visible: pending.Visible})
+		}
+	}
+	return nil
+}
+
+func restoreChains(chains []chain.Chain, snapshots []chain.ChainSnapshot, table chain.TransactionTable) error {
+
+	if len(snapshots) != len(chains) {
+		return fmt.Errorf("expected %d block trees, got %d", len(chains), len(snapshots))
+	}
+
+	for i := range chains {
+		if err := chains[i].Restore(snapshots[i], table); err != nil {
+			return fmt.Errorf("block tree of shard %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (simulation *Simulation) restoreBlocks(actor int, snapshots []chain.BlockSnapshot, table chain.TransactionTable) error {
+	for i := range snapshots {
+		block, err := snapshots[i].Restore(table)
+		if err != nil {
+			return err
+		}
+		simulation.channels.sendBlock(actor, block)
+	}
+	return nil
+}
+
+func (simulation *Simulation) restoreMessage(snapshot *MessageSnapshot, table chain.TransactionTable) error {
+
+	shardCount := simulation.config.ShardCount
+	if snapshot.From < 0 || snapshot.From > shardCount || snapshot.To < 0 || snapshot.To > shardCount {
+		return fmt.Errorf("unknown actor")
+	}
+	if snapshot.At < simulation.clock.Now() {
+		return fmt.Errorf("delivery at %v before the snapshot", snapshot.At)
+	}
+
+	message := &message{from: snapshot.From, to: snapshot.To, at: snapshot.At}
+	switch {
+	case snapshot.Block != nil:
+		block, err := snapshot.Block.Restore(table)
+		if err != nil {
+			return err
+		}
+		message.block = block
+	case snapshot.Finalisation != nil:
+		finalisation, err := snapshot.Finalisation.Restore(table)
+		if err != nil {
+			return err
+		}
+		message.finalisation = finalisation
+	default:
+		return fmt.Errorf("neither block nor finalisation")
+	}
+
+	simulation.channels.resend(message)
+	return nil
+}
+
+// Write snapshot as JSON.
+func (snapshot *Snapshot) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// Read snapshot from JSON
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("snapshot: %v", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,70 @@
+package simulation
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+
+	config := DefaultConfig()
+	config.Seed = 2
+	config.DebugShard = 0
+	config.Byzantine = ByzantineShards{{Shard: 2, Behaviours: []string{Withhold}, Probability: 1}}
+
+	eventClock := clock.NewEventClock()
+	simulation := Simulation{}
+	simulation.Init(&config, eventClock)
+	simulation.Start()
+	eventClock.Advance(90 * time.Second)
+	simulation.Stop()
+
+	// Snapshot survives encoding
+	snapshot := simulation.Snapshot()
+	if len(snapshot.Shards[1].Withheld) == 0 {
+		t.Errorf("expected blocks withheld by shard 2")
+	}
+	var buffer bytes.Buffer
+	if err := snapshot.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadSnapshot(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredClock := clock.NewEventClockAt(decoded.Time)
+	restored := Simulation{}
+	if err := restored.Restore(decoded, restoredClock); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(restored.DAG(), simulation.DAG()) {
+		t.Errorf("restored block trees differ")
+	}
+	if !reflect.DeepEqual(restored.Snapshot(), snapshot) {
+		t.Errorf("snapshot of restored simulation differs")
+	}
+	for shard := 1; shard <= config.ShardCount; shard++ {
+		if restored.Beacon().Validators().ShardStake(shard) != simulation.Beacon().Validators().ShardStake(shard) {
+			t.Errorf("stake of shard %d differs", shard)
+		}
+	}
+
+	// Restored simulation continues
+	height := restored.Beacon().Finalisation().Height
+	restored.Start()
+	restoredClock.Advance(90 * time.Second)
+	restored.Stop()
+
+	if restored.Clock().Now() != 180*time.Second {
+		t.Errorf("expected restored clock at 3m, got %v", restored.Clock().Now())
+	}
+	if restored.Beacon().Finalisation().Height <= height {
+		t.Errorf("restored simulation did not finalise, height %d", height)
+	}
+}
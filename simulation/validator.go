@@ -0,0 +1,271 @@
+package simulation
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+// Validator of a shard committee with its identity in the keyring
+type Validator struct {
+	identity *Identity
+	balance  int64
+}
+
+// ValidatorRegistry keeps the stake of all validators by public key and the committee of every shard.
+type ValidatorRegistry struct {
+	validators map[string]*Validator
+	committees [][]*Validator
+	history    []StakeRecord
+}
+
+// Stake of every shard committee (index shard) after a finalisation
+type StakeRecord struct {
+	Height int     `json:"height"`
+	Stake  []int64 `json:"stake"`
+}
+
+// Every identity of the shard committees of the keyring starts with stake
+func (registry *ValidatorRegistry) init(keys *Keyring, stake int64) {
+
+	registry.validators = make(map[string]*Validator)
+	registry.committees = make([][]*Validator, len(keys.committees))
+	registry.history = make([]StakeRecord, 0)
+
+	for shard := 1; shard < len(keys.committees); shard++ {
+		registry.committees[shard] = make([]*Validator, len(keys.Committee(shard)))
+
+		for i, identity := range keys.Committee(shard) {
+			validator := &Validator{
+				identity: identity,
+				balance:  stake,
+			}
+			registry.validators[string(identity.PublicKey)] = validator
+			registry.committees[shard][i] = validator
+		}
+	}
+
+	registry.record(0)
+}
+
+// Committee of validators of shard
+func (registry *ValidatorRegistry) Committee(shard int) []*Validator {
+	return registry.committees[shard]
+}
+
+// Penalise every validator of the shard committee, balances do not drop below zero.
+func (registry *ValidatorRegistry) Penalise(shard int, penalty int64) {
+	for _, validator := range registry.committees[shard] {
+		validator.balance -= penalty
+		if validator.balance < 0 {
+			validator.balance = 0
+		}
+	}
+}
+
+// Slash the full stake of the validator with identity, e.g. for signing two blocks in one slot.
+func (registry *ValidatorRegistry) Slash(identity *Identity) {
+	if validator, ok := registry.validators[string(identity.PublicKey)]; ok {
+		validator.balance = 0
+	}
+}
+
+// Stake of the validator with identity, 0 if it is not registered
+func (registry *ValidatorRegistry) Balance(identity *Identity) int64 {
+	if validator, ok := registry.validators[string(identity.PublicKey)]; ok {
+		return validator.balance
+	}
+	return 0
+}
+
+// Total stake of the shard committee
+func (registry *ValidatorRegistry) ShardStake(shard int) int64 {
+	stake := int64(0)
+	for _, validator := range registry.committees[shard] {
+		stake += validator.balance
+	}
+	return stake
+}
+
+// Record the stake of every shard committee at finalisation height
+func (registry *ValidatorRegistry) record(height int) {
+
+	stake := make([]int64, len(registry.committees))
+	for shard := 1; shard < len(registry.committees); shard++ {
+		stake[shard] = registry.ShardStake(shard)
+	}
+
+	registry.history = append(registry.history, StakeRecord{Height: height, Stake: stake})
+}
+
+// Stake of every shard committee over time
+func (registry *ValidatorRegistry) History() []StakeRecord {
+	return registry.history
+}
+
+// Write stake history as CSV, one row per finalisation and one column per shard.
+func (registry *ValidatorRegistry) WriteHistoryCSV(w io.Writer) error {
+
+	writer := csv.NewWriter(w)
+
+	header := []string{"height"}
+	for shard := 1; shard < len(registry.committees); shard++ {
+		header = append(header, fmt.Sprintf("shard %d", shard))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range registry.history {
+		row := []string{strconv.Itoa(record.Height)}
+		for shard := 1; shard < len(record.Stake); shard++ {
+			row = append(row, strconv.FormatInt(record.Stake[shard], 10))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Serialisable balances by hex encoded public key and stake history of the validators
+type ValidatorsSnapshot struct {
+	Balances map[string]int64 `json:"balances"`
+	History  []StakeRecord    `json:"history"`
+}
+
+func (registry *ValidatorRegistry) snapshot() ValidatorsSnapshot {
+
+	balances := make(map[string]int64, len(registry.validators))
+	for key, validator := range registry.validators {
+		balances[hex.EncodeToString([]byte(key))] = validator.balance
+	}
+
+	return ValidatorsSnapshot{Balances: balances, History: registry.history}
+}
+
+// Restore balances and history, the registry must be initialised with the same identities.
+func (registry *ValidatorRegistry) restore(snapshot ValidatorsSnapshot) error {
+
+	if len(snapshot.Balances) != len(registry.validators) {
+		return fmt.Errorf("expected balances of %d validators, got %d", len(registry.validators), len(snapshot.Balances))
+	}
+
+	for key, validator := range registry.validators {
+		balance, ok := snapshot.Balances[hex.EncodeToString([]byte(key))]
+		if !ok {
+			return fmt.Errorf("no balance of validator %s", validator.identity.Name)
+		}
+		validator.balance = balance
+	}
+	registry.history = snapshot.History
+	return nil
+}
+
+// Identity of a validator, named "shard-index" after its place in the committee, shard 0 for the beacon committee
+type Identity struct {
+	Name       string
+	Shard      int
+	PublicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+// Keyring holds the identity of every validator by shard committee, in the order of the validator registry,
+// and of the beacon committee (index 0). It selects the proposer of every slot and does not change once
+// initialised, it is shared by all actors.
+type Keyring struct {
+	seed       int64
+	committees [][]*Identity
+}
+
+// Key pairs are derived from the seed, so a restored simulation has the same identities.
+func (keyring *Keyring) init(shardCount int, committeeSize int, beaconCommitteeSize int, seed int64) {
+
+	keyring.seed = seed
+	keyring.committees = make([][]*Identity, shardCount+1)
+
+	// Shard committees first, the beacon committee last
+	keys := rand.New(rand.NewSource(seed))
+	for shard := 1; shard <= shardCount; shard++ {
+		keyring.committees[shard] = newCommittee(shard, committeeSize, keys)
+	}
+	keyring.committees[0] = newCommittee(0, beaconCommitteeSize, keys)
+}
+
+// Committee of shard with key pairs from the random keys
+func newCommittee(shard int, size int, keys *rand.Rand) []*Identity {
+
+	committee := make([]*Identity, size)
+	for i := range committee {
+		keySeed := make([]byte, ed25519.SeedSize)
+		keys.Read(keySeed)
+		privateKey := ed25519.NewKeyFromSeed(keySeed)
+
+		committee[i] = &Identity{
+			Name:       fmt.Sprintf("%d-%d", shard, i),
+			Shard:      shard,
+			PublicKey:  privateKey.Public().(ed25519.PublicKey),
+			privateKey: privateKey,
+		}
+	}
+	return committee
+}
+
+// Identities of the committee of shard, 0 for the beacon committee
+func (keyring *Keyring) Committee(shard int) []*Identity {
+	return keyring.committees[shard]
+}
+
+// Identity of the committee of shard by name, nil if there is none
+func (keyring *Keyring) Identity(shard int, name string) *Identity {
+	for _, identity := range keyring.committees[shard] {
+		if identity.Name == name {
+			return identity
+		}
+	}
+	return nil
+}
+
+// Proposer of the slot of shard, picked pseudo-randomly from the committee by every actor alike
+func (keyring *Keyring) Proposer(shard int, slot int) *Identity {
+
+	var input [24]byte
+	binary.BigEndian.PutUint64(input[0:], uint64(keyring.seed))
+	binary.BigEndian.PutUint64(input[8:], uint64(shard))
+	binary.BigEndian.PutUint64(input[16:], uint64(slot))
+	hash := sha256.Sum256(input[:])
+
+	committee := keyring.committees[shard]
+	return committee[binary.BigEndian.Uint64(hash[:8])%uint64(len(committee))]
+}
+
+// Verify the block is signed by the proposer of its slot
+func (keyring *Keyring) VerifyBlock(block *chain.Block) error {
+
+	if block.Shard < 1 || block.Shard >= len(keyring.committees) || block.Slot < 1 {
+		return fmt.Errorf("block %x: unknown shard %d or slot %d", block.Hash, block.Shard, block.Slot)
+	}
+
+	proposer := keyring.Proposer(block.Shard, block.Slot)
+	if block.Validator != proposer.Name {
+		return fmt.Errorf("block %x: validator %s is not the proposer %s of slot %d", block.Hash, block.Validator, proposer.Name, block.Slot)
+	}
+	return block.VerifySignature(proposer.PublicKey)
+}
+
+// Hash the block and sign it as validator
+func (identity *Identity) sign(block *chain.Block) {
+	block.Validator = identity.Name
+	block.SetHash()
+	block.Sign(identity.privateKey)
+}
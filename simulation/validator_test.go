@@ -0,0 +1,78 @@
+package simulation
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+)
+
+func TestKeyring(t *testing.T) {
+
+	keyring, same, other := Keyring{}, Keyring{}, Keyring{}
+	keyring.init(2, 4, 3, 1)
+	same.init(2, 4, 3, 1)
+	other.init(2, 4, 3, 2)
+
+	// Identities follow from the seed
+	if !bytes.Equal(keyring.Committee(2)[3].PublicKey, same.Committee(2)[3].PublicKey) {
+		t.Errorf("expected the same keys for the same seed")
+	}
+	if bytes.Equal(keyring.Committee(2)[3].PublicKey, other.Committee(2)[3].PublicKey) {
+		t.Errorf("expected other keys for another seed")
+	}
+	if name := keyring.Committee(2)[3].Name; name != "2-3" {
+		t.Errorf("expected validator 2-3, got %s", name)
+	}
+
+	// Every validator proposes some slots, every actor selects the same proposer
+	proposers := make(map[string]bool)
+	for slot := 1; slot <= 100; slot++ {
+		proposer := keyring.Proposer(1, slot)
+		if proposer.Shard != 1 || proposer != keyring.Proposer(1, slot) || proposer.Name != same.Proposer(1, slot).Name {
+			t.Fatalf("slot %d: unexpected proposer %s", slot, proposer.Name)
+		}
+		proposers[proposer.Name] = true
+	}
+	if len(proposers) != 4 {
+		t.Errorf("expected 4 proposers, got %d", len(proposers))
+	}
+}
+
+func TestKeyringVerifyBlock(t *testing.T) {
+
+	keyring := Keyring{}
+	keyring.init(2, 4, 3, 1)
+
+	newBlock := func(shard int, slot int) *chain.Block {
+		return &chain.Block{Shard: shard, ParentHash: []byte("parent"), Slot: slot}
+	}
+
+	block := newBlock(1, 5)
+	keyring.Proposer(1, 5).sign(block)
+	if err := keyring.VerifyBlock(block); err != nil {
+		t.Errorf("expected block of proposer accepted: %v", err)
+	}
+
+	// Committee member which is not the proposer of the slot
+	impersonated := newBlock(1, 5)
+	for _, identity := range keyring.Committee(1) {
+		if identity != keyring.Proposer(1, 5) {
+			identity.sign(impersonated)
+			break
+		}
+	}
+
+	// Proposer of the slot in another shard, and a proposer name with the key of another validator
+	otherShard := newBlock(1, 5)
+	keyring.Proposer(2, 5).sign(otherShard)
+	forged := newBlock(1, 5)
+	keyring.Committee(2)[0].sign(forged)
+	forged.Validator = keyring.Proposer(1, 5).Name
+
+	for name, block := range map[string]*chain.Block{"impersonated": impersonated, "other shard": otherShard, "forged": forged, "unknown shard": newBlock(3, 5)} {
+		if err := keyring.VerifyBlock(block); err == nil {
+			t.Errorf("expected %s block rejected", name)
+		}
+	}
+}
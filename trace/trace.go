@@ -0,0 +1,152 @@
+// Package trace records the events of a simulation run as JSON Lines.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+// Type of event
+type Type string
+
+const (
+	BlockProduced        Type = "blockProduced"
+	BlockReceived        Type = "blockReceived"
+	TransactionGenerated Type = "transactionGenerated"
+	FinalisationProposed Type = "finalisationProposed"
+	FinalisationReceived Type = "finalisationReceived"
+	BlockInvalidated     Type = "blockInvalidated"
+	BlockFinalised       Type = "blockFinalised"
+)
+
+// Beacon actor, shards are identified by their shard id.
+const Beacon = 0
+
+// Event of an actor at simulation time, one line in the trace. Blocks, transactions and finalisations are
+// shared by all actors and never change once published, the event is encoded when it is recorded.
+type Event struct {
+	Time         time.Duration       `json:"time"`
+	Actor        int                 `json:"actor"`
+	Type         Type                `json:"type"`
+	Shard        int                 `json:"shard,omitempty"`
+	Hash         []byte              `json:"hash,omitempty"`
+	Block        *chain.Block        `json:"block,omitempty"`
+	Transaction  *chain.Transaction  `json:"transaction,omitempty"`
+	Finalisation *chain.Finalisation `json:"finalisation,omitempty"`
+}
+
+// Log writes the events of all actors, safe for concurrent use.
+type Log struct {
+	mutex   sync.Mutex
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error
+}
+
+// Create log writing JSON Lines to w, call Flush when the run is done.
+func NewLog(w io.Writer) *Log {
+	writer := bufio.NewWriter(w)
+	return &Log{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// Write event, the first error is kept and returned by Flush.
+func (log *Log) Write(event Event) {
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if log.err == nil {
+		log.err = log.encoder.Encode(event)
+	}
+}
+
+// Flush buffered events and return the first error.
+func (log *Log) Flush() error {
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if log.err == nil {
+		log.err = log.writer.Flush()
+	}
+	return log.err
+}
+
+// Read all events of a trace, ordered by time. Events of a single actor keep their order.
+func Read(r io.Reader) ([]Event, error) {
+
+	events := make([]Event, 0)
+	decoder := json.NewDecoder(r)
+
+	for decoder.More() {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			return nil, fmt.Errorf("event %d: %v", len(events)+1, err)
+		}
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time < events[j].Time })
+	return events, nil
+}
+
+// Recorder of a single actor (beacon or shard), timestamps events with the simulation clock.
+// Recorders of all actors record concurrently. A nil recorder records nothing.
+type Recorder struct {
+	log   *Log
+	actor int
+	clock clock.Clock
+}
+
+// Recorder of events of actor
+func (log *Log) Recorder(actor int, clock clock.Clock) *Recorder {
+	return &Recorder{log: log, actor: actor, clock: clock}
+}
+
+func (recorder *Recorder) record(event Event) {
+	if recorder == nil {
+		return
+	}
+	event.Time = recorder.clock.Now()
+	event.Actor = recorder.actor
+	recorder.log.Write(event)
+}
+
+func (recorder *Recorder) BlockProduced(block *chain.Block) {
+	recorder.record(Event{Type: BlockProduced, Shard: block.Shard, Hash: block.Hash, Block: block})
+}
+
+func (recorder *Recorder) BlockReceived(block *chain.Block) {
+	recorder.record(Event{Type: BlockReceived, Shard: block.Shard, Hash: block.Hash, Block: block})
+}
+
+func (recorder *Recorder) TransactionGenerated(tx *chain.Transaction) {
+	recorder.record(Event{Type: TransactionGenerated, Shard: tx.SourceShard, Transaction: tx})
+}
+
+func (recorder *Recorder) FinalisationProposed(finalisation *chain.Finalisation) {
+	recorder.record(Event{Type: FinalisationProposed, Finalisation: finalisation})
+}
+
+func (recorder *Recorder) FinalisationReceived(finalisation *chain.Finalisation) {
+	recorder.record(Event{Type: FinalisationReceived, Finalisation: finalisation})
+}
+
+func (recorder *Recorder) BlockInvalidated(block *chain.Block) {
+	recorder.record(Event{Type: BlockInvalidated, Shard: block.Shard, Hash: block.Hash})
+}
+
+func (recorder *Recorder) BlockFinalised(block *chain.Block) {
+	recorder.record(Event{Type: BlockFinalised, Shard: block.Shard, Hash: block.Hash})
+}
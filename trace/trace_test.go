@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sjoerdwels/Guaranteed-TX/chain"
+	"github.com/sjoerdwels/Guaranteed-TX/clock"
+)
+
+func TestRecorder(t *testing.T) {
+
+	var buffer bytes.Buffer
+	log := NewLog(&buffer)
+	recorder := log.Recorder(2, clock.NewEventClock())
+
+	tx := chain.Transaction{SourceShard: 1, TargetShard: 2, Data: "data"}
+	tx.SetHash()
+
+	// Chain of shard 2 with a block processing a transaction which is not available
+	blockTree := chain.Chain{}
+	blockTree.Init(2, clock.NewEventClock())
+	blockTree.SetRecorder(recorder)
+
+	block := chain.Block{Shard: 2, ParentHash: blockTree.GenesisBlock().Block().Hash, TXIn: []*chain.Transaction{&tx}}
+	block.SetHash()
+	recorder.BlockReceived(&block)
+	blockTree.Insert(&block)
+
+	blockTree.UpdateConsistency(nil)
+	blockTree.UpdateConsistency(nil)
+	blockTree.UpdateConsistency([]*chain.Transaction{&tx})
+	blockTree.Finalise(block.Hash)
+
+	var disabled *Recorder
+	disabled.TransactionGenerated(&tx)
+
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make([]Event, 0)
+	scanner := bufio.NewScanner(&buffer)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	// Invalidation is recorded once, finalisation of the genesis block is not recorded
+	expected := []Type{BlockReceived, BlockInvalidated, BlockFinalised}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if event.Type != expected[i] || event.Actor != 2 || event.Shard != 2 || !bytes.Equal(event.Hash, block.Hash) {
+			t.Errorf("unexpected event %d: %+v", i, event)
+		}
+	}
+	if events[0].Block == nil || events[0].Block.TXIn[0].Hash != tx.Hash {
+		t.Errorf("received block not recorded")
+	}
+}
+
+// Every actor records the same published block concurrently, run with -race.
+func TestRecordShared(t *testing.T) {
+
+	var buffer bytes.Buffer
+	log := NewLog(&buffer)
+	eventClock := clock.NewEventClock()
+
+	tx := chain.Transaction{SourceShard: 1, TargetShard: 2, Data: "data", Created: 3}
+	tx.SetHash()
+	block := chain.Block{Shard: 1, ParentHash: []byte("Shard 1"), Slot: 1, TXOut: []*chain.Transaction{&tx}}
+	block.SetHash()
+
+	const actors = 4
+	done := make(chan bool)
+	for actor := 0; actor < actors; actor++ {
+		recorder := log.Recorder(actor, eventClock)
+		go func() {
+			for i := 0; i < 10; i++ {
+				recorder.BlockReceived(&block)
+			}
+			done <- true
+		}()
+	}
+	for actor := 0; actor < actors; actor++ {
+		<-done
+	}
+
+	if err := log.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != actors*10 {
+		t.Fatalf("expected %d events, got %d", actors*10, len(events))
+	}
+	for _, event := range events {
+		if event.Block == nil || *event.Block.TXOut[0] != tx {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	}
+}